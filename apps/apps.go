@@ -0,0 +1,9 @@
+// Package apps links every app into the binary. Each app registers its
+// module factory with the gateway from an init function, so enabling a new
+// app only requires adding its import here.
+package apps
+
+import (
+	_ "github.com/goku-m/main/apps/task"
+	_ "github.com/goku-m/main/apps/todo"
+)
@@ -47,18 +47,46 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 		checks["database"] = map[string]interface{}{
 			"status":        "unhealthy",
 			"response_time": time.Since(dbStart).String(),
-			"error":         err.Error(),
+			"error":         h.server.Config.Redact(err.Error()),
+			"pool":          h.server.DB.Stats(),
 		}
 		isHealthy = false
-		logger.Error().Err(err).Dur("response_time", time.Since(dbStart)).Msg("database health check failed")
+		logger.Error().Str("error", h.server.Config.Redact(err.Error())).Dur("response_time", time.Since(dbStart)).Msg("database health check failed")
 	} else {
 		checks["database"] = map[string]interface{}{
 			"status":        "healthy",
 			"response_time": time.Since(dbStart).String(),
+			"pool":          h.server.DB.Stats(),
+			"queries":       h.server.DB.SlowestQueries(5),
 		}
 		logger.Info().Dur("response_time", time.Since(dbStart)).Msg("database health check passed")
 	}
 
+	// Check the read replica. Like Redis it does not fail the check: the
+	// primary still serves writes.
+	if replicaStats, ok := h.server.DB.ReplicaStats(); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		replicaStart := time.Now()
+		if err := h.server.DB.Replica.Ping(ctx); err != nil {
+			checks["database_replica"] = map[string]interface{}{
+				"status":        "unhealthy",
+				"response_time": time.Since(replicaStart).String(),
+				"error":         h.server.Config.Redact(err.Error()),
+				"pool":          replicaStats,
+			}
+			logger.Error().Str("error", h.server.Config.Redact(err.Error())).Dur("response_time", time.Since(replicaStart)).Msg("database replica health check failed")
+		} else {
+			checks["database_replica"] = map[string]interface{}{
+				"status":        "healthy",
+				"response_time": time.Since(replicaStart).String(),
+				"pool":          replicaStats,
+			}
+			logger.Info().Dur("response_time", time.Since(replicaStart)).Msg("database replica health check passed")
+		}
+	}
+
 	// Check Redis connectivity
 	if h.server.Redis != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -69,9 +97,9 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 			checks["redis"] = map[string]interface{}{
 				"status":        "unhealthy",
 				"response_time": time.Since(redisStart).String(),
-				"error":         err.Error(),
+				"error":         h.server.Config.Redact(err.Error()),
 			}
-			logger.Error().Err(err).Dur("response_time", time.Since(redisStart)).Msg("redis health check failed")
+			logger.Error().Str("error", h.server.Config.Redact(err.Error())).Dur("response_time", time.Since(redisStart)).Msg("redis health check failed")
 		} else {
 			checks["redis"] = map[string]interface{}{
 				"status":        "healthy",
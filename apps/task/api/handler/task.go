@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/goku-m/main/apps/task/api/model/task"
@@ -10,7 +13,12 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/goku-m/main/apps/task/api/service"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/errs"
+	"github.com/goku-m/main/internal/shared/render"
 	"github.com/goku-m/main/internal/shared/server"
+	"github.com/goku-m/main/internal/shared/urls"
+	"github.com/goku-m/main/internal/shared/validation"
 	"github.com/labstack/echo/v4"
 )
 
@@ -96,12 +104,31 @@ func (h *TaskHandler) UpdateTaskPage(c echo.Context) error {
 		Description: desc,               // if pointer
 		Priority:    string(t.Priority), // adjust types
 		Status:      string(t.Status),
+		Version:     strconv.Itoa(t.Version),
 	}
 
+	c.Response().Header().Set("ETag", t.ETag())
 	return pages.EditTask(view).Render(c.Request().Context(), c.Response())
 
 }
 
+// GetTask returns a task with its version as the ETag, for updates sent
+// with If-Match.
+func (h *TaskHandler) GetTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	t, err := h.taskService.GetTaskByID(c, taskID)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("ETag", t.ETag())
+	return c.JSON(http.StatusOK, t)
+}
+
 func (h *TaskHandler) CreateTask(c echo.Context) error {
 	// taskID := middleware.GetTaskID(c)
 
@@ -128,7 +155,7 @@ func (h *TaskHandler) CreateTask(c echo.Context) error {
 	}
 
 	// Redirect back to list (refresh)
-	return c.Redirect(http.StatusSeeOther, "/task")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "task.home"))
 }
 
 func (h *TaskHandler) UpdateTask(c echo.Context) error {
@@ -179,13 +206,71 @@ func (h *TaskHandler) UpdateTask(c echo.Context) error {
 		}
 	}
 
+	// The API sends the version it read as If-Match, the form as a field
+	ifMatch, err := task.ParseIfMatch(c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid If-Match header")
+	}
+	payload.IfMatch = ifMatch
+	if ifMatch == nil {
+		if v := strings.TrimSpace(c.FormValue("version")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid version")
+			}
+			payload.Version = &n
+		}
+	}
+
 	// 4) Update
-	if _, err := h.taskService.UpdateTask(c, payload); err != nil {
+	updated, err := h.taskService.UpdateTask(c, payload)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusConflict {
+			return h.renderUpdateConflict(c, payload)
+		}
 		return err
 	}
+	c.Response().Header().Set("ETag", updated.ETag())
 
 	// 5) Redirect back (refresh)
-	return c.Redirect(http.StatusSeeOther, "/task")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "task.home"))
+}
+
+// renderUpdateConflict asks the user of the form whether to reload the task
+// or overwrite it with the values they submitted.
+func (h *TaskHandler) renderUpdateConflict(c echo.Context, payload *task.UpdateTaskPayload) error {
+	current, err := h.taskService.GetTaskByID(c, payload.ID)
+	if err != nil {
+		return err
+	}
+
+	mine := pages.TaskView{
+		ID:          payload.ID.String(),
+		Title:       c.FormValue("title"),
+		Description: c.FormValue("description"),
+		Priority:    c.FormValue("priority"),
+		Status:      c.FormValue("status"),
+	}
+
+	currentDesc := ""
+	if current.Description != nil {
+		currentDesc = *current.Description
+	}
+
+	view := pages.TaskView{
+		ID:          current.ID.String(),
+		Title:       current.Title,
+		Description: currentDesc,
+		Priority:    string(current.Priority),
+		Status:      string(current.Status),
+		Version:     strconv.Itoa(current.Version),
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().Header().Set("ETag", current.ETag())
+	c.Response().WriteHeader(http.StatusConflict)
+	return pages.EditTaskConflict(mine, view).Render(c.Request().Context(), c.Response())
 }
 
 func (h *TaskHandler) DeleteTask(c echo.Context) error {
@@ -204,5 +289,137 @@ func (h *TaskHandler) DeleteTask(c echo.Context) error {
 		return err
 	}
 
-	return c.Redirect(http.StatusSeeOther, "/task")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "task.home"))
+}
+
+func (h *TaskHandler) GetTaskTrashPage(c echo.Context) error {
+	tasks, err := h.taskService.GetDeletedTasks(c)
+	if err != nil {
+		return err
+	}
+
+	view := make([]pages.TaskView, 0, len(tasks))
+	for _, t := range tasks {
+		view = append(view, pages.TaskView{
+			ID:        t.ID.String(),
+			Title:     t.Title,
+			Priority:  string(t.Priority),
+			Status:    string(t.Status),
+			DeletedAt: t.DeletedAt,
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	return pages.Trash(view).Render(c.Request().Context(), c.Response())
+}
+
+func (h *TaskHandler) RestoreTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.FormValue("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if _, err := h.taskService.RestoreTask(c, taskID); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "task.trash"))
+}
+
+func (h *TaskHandler) PurgeTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.FormValue("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.taskService.PurgeTask(c, taskID); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "task.trash"))
+}
+
+// GetTaskHistory returns the audit log of tasks, filtered by the query.
+func (h *TaskHandler) GetTaskHistory(c echo.Context) error {
+	query := &task.GetTaskHistoryQuery{}
+	if err := validation.BindAndValidate(c, query); err != nil {
+		return err
+	}
+
+	history, err := h.taskService.GetTaskHistory(c, query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h *TaskHandler) GetTaskHistoryPage(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	page, limit := 1, 100
+	history, err := h.taskService.GetTaskHistory(c, &task.GetTaskHistoryQuery{
+		TaskID: &taskID,
+		Page:   &page,
+		Limit:  &limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	view := make([]pages.HistoryEntryView, 0, len(history.Data))
+	for _, e := range history.Data {
+		entry := pages.HistoryEntryView{
+			At:        e.CreatedAt,
+			Operation: string(e.Operation),
+		}
+		if e.Actor != nil {
+			entry.Actor = *e.Actor
+		}
+		if e.RequestID != nil {
+			entry.RequestID = *e.RequestID
+		}
+
+		fields := make([]string, 0, len(e.Diff))
+		for field := range e.Diff {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			change := e.Diff[field]
+			entry.Changes = append(entry.Changes, pages.HistoryChangeView{
+				Field:  field,
+				Before: historyValue(change.Before),
+				After:  historyValue(change.After),
+			})
+		}
+
+		view = append(view, entry)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	return pages.History(view).Render(c.Request().Context(), c.Response())
+}
+
+func historyValue(v any) string {
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprint(v)
+}
+
+// StreamTaskChanges streams inserts, updates and deletes of tasks, made by
+// any replica or by hand, as server-sent events.
+func (h *TaskHandler) StreamTaskChanges(c echo.Context) error {
+	if h.server.Listener == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "change feed unavailable")
+	}
+
+	sub := h.server.Listener.Subscribe(database.ChangeChannel("task", "tasks"))
+	defer sub.Close()
+
+	return render.StreamChanges(c, sub)
 }
@@ -27,6 +27,12 @@ type UpdateTaskPayload struct {
 	Description *string   `json:"description" validate:"omitempty,max=1000"`
 	Status      *Status   `json:"status" validate:"omitempty,oneof=draft active completed archived"`
 	Priority    *Priority `json:"priority" validate:"omitempty,oneof=low medium high"`
+	// Version the update is based on; when set, the update is rejected with
+	// a conflict if the task has changed since.
+	Version *int `json:"version" validate:"omitempty,min=1"`
+	// IfMatch is the request's If-Match precondition; when set, the update
+	// is rejected with precondition failed unless it matches the task.
+	IfMatch *IfMatch `json:"-"`
 }
 
 func (p *UpdateTaskPayload) Validate() error {
@@ -97,3 +103,35 @@ func (p *DeleteTaskPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
 }
+
+// ------------------------------------------------------------
+
+type GetTaskHistoryQuery struct {
+	TaskID    *uuid.UUID `query:"task_id"`
+	Actor     *string    `query:"actor" validate:"omitempty,min=1"`
+	Operation *string    `query:"operation" validate:"omitempty,oneof=create update delete restore purge"`
+	From      *time.Time `query:"from"`
+	To        *time.Time `query:"to"`
+	Page      *int       `query:"page" validate:"omitempty,min=1"`
+	Limit     *int       `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *GetTaskHistoryQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	// Set defaults for pagination
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
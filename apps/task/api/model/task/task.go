@@ -1,6 +1,10 @@
 package task
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/goku-m/main/apps/task/api/model"
@@ -32,6 +36,8 @@ type Task struct {
 	DueDate     *time.Time `json:"dueDate" db:"due_date"`
 	CompletedAt *time.Time `json:"completedAt" db:"completed_at"`
 	SortOrder   int        `json:"sortOrder" db:"sort_order"`
+	DeletedAt   *time.Time `json:"deletedAt" db:"deleted_at"`
+	Version     int        `json:"version" db:"version"`
 }
 
 type PopulatedTask struct {
@@ -58,3 +64,70 @@ type UserWeeklyStats struct {
 func (t *Task) IsOverdue() bool {
 	return t.DueDate != nil && t.DueDate.Before(time.Now()) && t.Status != StatusCompleted
 }
+
+// ETag is the entity tag of the task's current version.
+func (t *Task) ETag() string {
+	return strconv.Quote(strconv.Itoa(t.Version))
+}
+
+// IfMatch is a parsed If-Match header: the versions of a task that an
+// update may be applied to.
+type IfMatch struct {
+	// Any is set by "*", which matches every version of an existing task.
+	Any bool
+	// Versions are the versions named by the header's strong entity tags.
+	// Weak tags and tags that are not task versions are dropped, as they can
+	// never match.
+	Versions []int
+}
+
+// Matches reports whether version satisfies the precondition.
+func (m *IfMatch) Matches(version int) bool {
+	return m.Any || slices.Contains(m.Versions, version)
+}
+
+// ParseIfMatch parses an If-Match header, a list of entity tags or "*", and
+// returns nil for an empty header. If-Match uses the strong comparison of
+// RFC 9110, so weak tags (W/"3") are accepted but never match.
+func ParseIfMatch(header string) (*IfMatch, error) {
+	rest := strings.TrimSpace(header)
+	if rest == "" {
+		return nil, nil
+	}
+	if rest == "*" {
+		return &IfMatch{Any: true}, nil
+	}
+
+	m := &IfMatch{}
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+
+		weak := strings.HasPrefix(rest, "W/")
+		rest = strings.TrimPrefix(rest, "W/")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, fmt.Errorf("invalid If-Match %q: expected a quoted entity tag", header)
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid If-Match %q: unterminated entity tag", header)
+		}
+		tag := rest[1 : end+1]
+		rest = rest[end+2:]
+
+		if next := strings.TrimLeft(rest, " \t"); next != "" && next[0] != ',' {
+			return nil, fmt.Errorf("invalid If-Match %q: expected a comma after %q", header, tag)
+		}
+
+		if version, err := strconv.Atoi(tag); err == nil && !weak {
+			m.Versions = append(m.Versions, version)
+		}
+	}
+	if len(m.Versions) == 0 && !strings.Contains(header, `"`) {
+		return nil, fmt.Errorf("invalid If-Match %q: no entity tags", header)
+	}
+
+	return m, nil
+}
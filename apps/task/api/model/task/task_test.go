@@ -0,0 +1,88 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		wantNil  bool
+		wantAny  bool
+		versions []int
+		wantErr  bool
+	}{
+		{header: "", wantNil: true},
+		{header: "   ", wantNil: true},
+		{header: "*", wantAny: true},
+		{header: ` * `, wantAny: true},
+		{header: `"3"`, versions: []int{3}},
+		{header: `W/"3"`, versions: nil},
+		{header: `"3", "4"`, versions: []int{3, 4}},
+		{header: `"3",W/"4" ,, "5"`, versions: []int{3, 5}},
+		{header: `"abc"`, versions: nil},
+		{header: `"abc", "7"`, versions: []int{7}},
+		{header: `"a,b", "7"`, versions: []int{7}},
+		{header: `3`, wantErr: true},
+		{header: `"3`, wantErr: true},
+		{header: `"3" "4"`, wantErr: true},
+		{header: `"3", *`, wantErr: true},
+		{header: `w/"3"`, wantErr: true},
+		{header: `,`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			m, err := ParseIfMatch(tt.header)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseIfMatch = %+v, want error", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseIfMatch: %v", err)
+			}
+			if tt.wantNil {
+				if m != nil {
+					t.Fatalf("ParseIfMatch = %+v, want nil", m)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatal("ParseIfMatch = nil")
+			}
+			if m.Any != tt.wantAny || !slices.Equal(m.Versions, tt.versions) {
+				t.Errorf("ParseIfMatch = %+v, want any %v versions %v", m, tt.wantAny, tt.versions)
+			}
+		})
+	}
+}
+
+func TestIfMatchMatches(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		want    bool
+	}{
+		{`*`, 9, true},
+		{`"3"`, 3, true},
+		{`"3"`, 4, false},
+		{`W/"4"`, 4, false},
+		{`W/"4", "4"`, 4, true},
+		{`"2", "4"`, 4, true},
+		{`"2", "4"`, 3, false},
+		{`"abc"`, 1, false},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseIfMatch(tt.header)
+		if err != nil {
+			t.Fatalf("ParseIfMatch(%q): %v", tt.header, err)
+		}
+		if got := m.Matches(tt.version); got != tt.want {
+			t.Errorf("ParseIfMatch(%q).Matches(%d) = %v, want %v", tt.header, tt.version, got, tt.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/goku-m/main/apps/task/api/model"
 	"github.com/goku-m/main/apps/task/api/model/task"
+	"github.com/goku-m/main/internal/shared/audit"
 	"github.com/goku-m/main/internal/shared/errs"
 	"github.com/goku-m/main/internal/shared/server"
 	"github.com/google/uuid"
@@ -23,7 +24,51 @@ func NewTaskRepository(server *server.Server) *TaskRepository {
 	return &TaskRepository{server: server}
 }
 
+// audit records a change of a task in the audit log. Writes call it in the
+// transaction making the change, so the entry commits with it.
+func (r *TaskRepository) audit(ctx context.Context, op audit.Operation, taskID uuid.UUID, before, after *task.Task) error {
+	return audit.Record(ctx, r.server.DB, op, "task", taskID.String(), before, after)
+}
+
+// lockTask reads a task, in the trash or not, and locks it until the
+// transaction ends, to audit what a write changes. It returns nil if there
+// is no such task.
+func (r *TaskRepository) lockTask(ctx context.Context, taskID uuid.UUID) (*task.Task, error) {
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, `SELECT * FROM tasks WHERE id=@id FOR UPDATE`, pgx.NamedArgs{
+		"id": taskID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock task for task_id=%s: %w", taskID.String(), err)
+	}
+
+	taskItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s: %w", taskID.String(), err)
+	}
+
+	return &taskItem, nil
+}
+
 func (r *TaskRepository) CreateTask(ctx context.Context, payload *task.CreateTaskPayload) (*task.Task, error) {
+	var created *task.Task
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = r.createTask(ctx, payload); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationCreate, created.ID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *TaskRepository) createTask(ctx context.Context, payload *task.CreateTaskPayload) (*task.Task, error) {
 	stmt := `
 		INSERT INTO
 			tasks (
@@ -48,7 +93,7 @@ func (r *TaskRepository) CreateTask(ctx context.Context, payload *task.CreateTas
 		priority = *payload.Priority
 	}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"title":       payload.Title,
 		"description": payload.Description,
 		"priority":    priority,
@@ -76,12 +121,13 @@ func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID uuid.UUID) (*ta
 	
 	WHERE
 		u.id=@id
+		AND u.deleted_at IS NULL
 	GROUP BY
 		u.id
 		
 `
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"id": taskID,
 	})
 	if err != nil {
@@ -90,7 +136,7 @@ func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID uuid.UUID) (*ta
 
 	taskItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[task.Task])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s task_id=%s: %w", taskID.String(), err)
+		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s: %w", taskID.String(), err)
 	}
 
 	return &taskItem, nil
@@ -104,9 +150,10 @@ func (r *TaskRepository) CheckTaskExists(ctx context.Context, taskID uuid.UUID)
 			tasks
 		WHERE
 			id=@id
+			AND deleted_at IS NULL
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"id": taskID,
 	})
 	if err != nil {
@@ -121,6 +168,35 @@ func (r *TaskRepository) CheckTaskExists(ctx context.Context, taskID uuid.UUID)
 	return &taskItem, nil
 }
 
+func (r *TaskRepository) GetOverdueTasks(ctx context.Context, now time.Time) ([]task.Task, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			tasks
+		WHERE
+			due_date < @now
+			AND status != 'completed'
+			AND deleted_at IS NULL
+		ORDER BY
+			due_date ASC
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"now": now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get overdue tasks query: %w", err)
+	}
+
+	tasks, err := pgx.CollectRows(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
 func (r *TaskRepository) GetTasks(
 	ctx context.Context,
 	query *task.GetTasksQuery,
@@ -134,7 +210,7 @@ func (r *TaskRepository) GetTasks(
 	`
 
 	args := pgx.NamedArgs{}
-	conditions := []string{}
+	conditions := []string{"t.deleted_at IS NULL"}
 
 	if query != nil {
 		if query.Status != nil {
@@ -161,21 +237,16 @@ func (r *TaskRepository) GetTasks(
 		}
 	}
 
-	if len(conditions) > 0 {
-		stmt += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	stmt += " WHERE " + strings.Join(conditions, " AND ")
 
 	// ----- count query -----
-	countStmt := "SELECT COUNT(*) FROM tasks t"
-	if len(conditions) > 0 {
-		countStmt += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	countStmt := "SELECT COUNT(*) FROM tasks t WHERE " + strings.Join(conditions, " AND ")
 
 	// If server/DB/Pool can be nil in your wiring, you may also want to guard here:
 	// if r == nil || r.server == nil || r.server.DB == nil || r.server.DB.Pool == nil { ... }
 
 	var total int
-	err := r.server.DB.Pool.QueryRow(ctx, countStmt, args).Scan(&total)
+	err := r.server.DB.Reader(ctx).QueryRow(ctx, countStmt, args).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count for tasks: %w", err)
 	}
@@ -228,7 +299,7 @@ func (r *TaskRepository) GetTasks(
 	args["limit"] = limit
 	args["offset"] = (page - 1) * limit
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get tasks query: %w", err)
 	}
@@ -260,6 +331,36 @@ func (r *TaskRepository) GetTasks(
 }
 
 func (r *TaskRepository) UpdateTask(ctx context.Context, payload *task.UpdateTaskPayload) (*task.Task, error) {
+	var updated *task.Task
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTask(ctx, payload.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.DeletedAt != nil {
+			code := "TASK_NOT_FOUND"
+			return errs.NewNotFoundError("task not found", false, &code)
+		}
+		// The row is locked, so the version cannot change before the update
+		if payload.IfMatch != nil && !payload.IfMatch.Matches(before.Version) {
+			return errs.NewPreconditionFailedError("task was changed by someone else", false)
+		}
+		if payload.Version != nil && *payload.Version != before.Version {
+			return errs.NewConflictError("task was changed by someone else", false)
+		}
+		if updated, err = r.updateTask(ctx, payload); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationUpdate, updated.ID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *TaskRepository) updateTask(ctx context.Context, payload *task.UpdateTaskPayload) (*task.Task, error) {
 	stmt := "UPDATE tasks SET "
 	args := pgx.NamedArgs{
 		"task_id": payload.ID,
@@ -297,11 +398,12 @@ func (r *TaskRepository) UpdateTask(ctx context.Context, payload *task.UpdateTas
 	if len(setClauses) == 0 {
 		return nil, errs.NewBadRequestError("no fields to update", false, nil, nil, nil)
 	}
+	setClauses = append(setClauses, "version = version + 1")
 
 	stmt += strings.Join(setClauses, ", ")
-	stmt += " WHERE id = @task_id  RETURNING *"
+	stmt += " WHERE id = @task_id AND deleted_at IS NULL RETURNING *"
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -314,24 +416,165 @@ func (r *TaskRepository) UpdateTask(ctx context.Context, payload *task.UpdateTas
 	return &updatedTask, nil
 }
 
+// DeleteTask moves the task to the trash.
 func (r *TaskRepository) DeleteTask(ctx context.Context, taskID uuid.UUID) error {
+	return r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		deleted, err := r.deleteTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationDelete, taskID, before, deleted)
+	})
+}
+
+func (r *TaskRepository) deleteTask(ctx context.Context, taskID uuid.UUID) (*task.Task, error) {
+	stmt := `
+		UPDATE tasks
+		SET
+			deleted_at = CURRENT_TIMESTAMP
+		WHERE
+			id=@task_id
+			AND deleted_at IS NULL
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"task_id": taskID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	taskItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TASK_NOT_FOUND"
+			return nil, errs.NewNotFoundError("task not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s: %w", taskID.String(), err)
+	}
+
+	return &taskItem, nil
+}
+
+// GetDeletedTasks lists the tasks in the trash, most recently deleted first.
+func (r *TaskRepository) GetDeletedTasks(ctx context.Context) ([]task.Task, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			tasks
+		WHERE
+			deleted_at IS NOT NULL
+		ORDER BY
+			deleted_at DESC
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get deleted tasks query: %w", err)
+	}
+
+	tasks, err := pgx.CollectRows(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// RestoreTask takes the task out of the trash.
+func (r *TaskRepository) RestoreTask(ctx context.Context, taskID uuid.UUID) (*task.Task, error) {
+	var restored *task.Task
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		if restored, err = r.restoreTask(ctx, taskID); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationRestore, taskID, before, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (r *TaskRepository) restoreTask(ctx context.Context, taskID uuid.UUID) (*task.Task, error) {
+	stmt := `
+		UPDATE tasks
+		SET
+			deleted_at = NULL
+		WHERE
+			id=@task_id
+			AND deleted_at IS NOT NULL
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"task_id": taskID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute restore task query for task_id=%s: %w", taskID.String(), err)
+	}
+
+	taskItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TASK_NOT_FOUND"
+			return nil, errs.NewNotFoundError("task not found in trash", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s: %w", taskID.String(), err)
+	}
+
+	return &taskItem, nil
+}
+
+// PurgeTask permanently deletes a task in the trash.
+func (r *TaskRepository) PurgeTask(ctx context.Context, taskID uuid.UUID) error {
+	return r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		purged, err := r.purgeTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationPurge, taskID, purged, nil)
+	})
+}
+
+func (r *TaskRepository) purgeTask(ctx context.Context, taskID uuid.UUID) (*task.Task, error) {
 	stmt := `
 		DELETE FROM tasks
 		WHERE
 			id=@task_id
+			AND deleted_at IS NOT NULL
+		RETURNING
+		*
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"task_id": taskID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		code := "TODO_NOT_FOUND"
-		return errs.NewNotFoundError("task not found", false, &code)
+	taskItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[task.Task])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TASK_NOT_FOUND"
+			return nil, errs.NewNotFoundError("task not found in trash", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:tasks for task_id=%s: %w", taskID.String(), err)
 	}
 
-	return nil
+	return &taskItem, nil
 }
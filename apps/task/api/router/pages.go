@@ -3,14 +3,15 @@ package router
 import (
 	"github.com/goku-m/main/apps/task/api/handler"
 	"github.com/goku-m/main/internal/shared/middleware"
-
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/routing"
 )
 
-func registerPagesRoutes(r *echo.Echo, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+func registerPagesRoutes(r *routing.Router, h *handler.Handlers, auth *middleware.AuthMiddleware) {
 
-	r.GET("/", h.Task.GetTaskPage)
-	r.GET("/create", h.Task.CreateTaskPage)
+	r.GET("/", h.Task.GetTaskPage).Name = "task.home"
+	r.GET("/create", h.Task.CreateTaskPage).Name = "task.create"
 	r.Use(auth.RequireAuthIP)
-	r.GET("/update/:id", h.Task.UpdateTaskPage)
+	r.GET("/update/:id", h.Task.UpdateTaskPage).Name = "task.edit"
+	r.GET("/trash", h.Task.GetTaskTrashPage).Name = "task.trash"
+	r.GET("/history/:id", h.Task.GetTaskHistoryPage).Name = "task.history"
 }
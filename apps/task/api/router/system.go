@@ -2,11 +2,10 @@ package router
 
 import (
 	"github.com/goku-m/main/apps/task/api/handler"
-
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/routing"
 )
 
-func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
+func registerSystemRoutes(r *routing.Router, h *handler.Handlers) {
 	r.GET("/status", h.Health.CheckHealth)
 
 	r.Static("/static", "./web/static")
@@ -3,17 +3,28 @@ package router
 import (
 	"github.com/goku-m/main/apps/task/api/handler"
 	"github.com/goku-m/main/internal/shared/middleware"
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/routing"
 )
 
-func registerTaskRoutes(r *echo.Group, h *handler.TaskHandler, auth *middleware.AuthMiddleware) {
+func registerTaskRoutes(r *routing.Group, h *handler.TaskHandler, auth *middleware.AuthMiddleware) {
 	// User operations
 	tasks := r.Group("/tasks")
 	// tasks.Use(auth.RequireAuthIP)
 
 	// Collection operations for pages
-	tasks.POST("/create", h.CreateTask)
-	tasks.POST("/delete", h.DeleteTask)
-	tasks.POST("/update/:id", h.UpdateTask)
+	tasks.POST("/create", h.CreateTask).Name = "task.api.create"
+	tasks.POST("/delete", h.DeleteTask).Name = "task.api.delete"
+	tasks.POST("/update/:id", h.UpdateTask).Name = "task.api.update"
+	tasks.POST("/restore", h.RestoreTask).Name = "task.api.restore"
+	tasks.POST("/purge", h.PurgeTask).Name = "task.api.purge"
+
+	// A single task with its version as the ETag, for updates sent with If-Match
+	tasks.GET("/:id", h.GetTask).Name = "task.api.get"
+
+	// Audit log of task changes, filtered by task_id, actor, operation, from and to
+	r.GET("/audit", h.GetTaskHistory).Name = "task.api.audit"
+
+	// Server-sent events for every change to tasks
+	tasks.GET("/changes", h.StreamTaskChanges).Name = "task.api.changes"
 
 }
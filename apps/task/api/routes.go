@@ -3,10 +3,10 @@ package api
 import (
 	"github.com/goku-m/main/apps/task/api/handler"
 	"github.com/goku-m/main/apps/task/api/router"
+	"github.com/goku-m/main/internal/shared/routing"
 	"github.com/goku-m/main/internal/shared/server"
-	"github.com/labstack/echo/v4"
 )
 
-func NewRouter(s *server.Server, h *handler.Handlers) *echo.Echo {
+func NewRouter(s *server.Server, h *handler.Handlers) *routing.Router {
 	return router.NewRouter(s, h)
 }
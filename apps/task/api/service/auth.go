@@ -11,7 +11,7 @@ type AuthService struct {
 }
 
 func NewAuthService(s *server.Server) *AuthService {
-	clerk.SetKey(s.Config.Auth.SecretKey)
+	clerk.SetKey(s.Config.Auth.SecretKey.Value())
 	return &AuthService{
 		server: s,
 	}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/goku-m/main/apps/task/api/model/task"
+	"github.com/goku-m/main/internal/shared/outbox"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// Task events, published through the outbox once the change has committed.
+const (
+	EventTaskCreated  = "task:created"
+	EventTaskUpdated  = "task:updated"
+	EventTaskDeleted  = "task:deleted"
+	EventTaskRestored = "task:restored"
+	EventTaskPurged   = "task:purged"
+)
+
+// TaskEventPayload is the job payload of every task event.
+type TaskEventPayload struct {
+	TaskID   string        `json:"task_id"`
+	Title    string        `json:"title,omitempty"`
+	Status   task.Status   `json:"status,omitempty"`
+	Priority task.Priority `json:"priority,omitempty"`
+}
+
+// TaskPurgedEvent is the event of a task deleted for good, by the user or by
+// the trash purge. Only the ID is left to report.
+func TaskPurgedEvent(taskID uuid.UUID) outbox.Message {
+	return outbox.Message{
+		AggregateType: "task",
+		AggregateID:   taskID.String(),
+		Type:          EventTaskPurged,
+		Payload:       TaskEventPayload{TaskID: taskID.String()},
+	}
+}
+
+func taskEvent(eventType string, t *task.Task) outbox.Message {
+	return outbox.Message{
+		AggregateType: "task",
+		AggregateID:   t.ID.String(),
+		Type:          eventType,
+		Payload: TaskEventPayload{
+			TaskID:   t.ID.String(),
+			Title:    t.Title,
+			Status:   t.Status,
+			Priority: t.Priority,
+		},
+	}
+}
+
+// HandleTaskEvent processes a task event delivered from the outbox. Delivery
+// is at least once, so handlers of side effects must tolerate repeats.
+func (s *TaskService) HandleTaskEvent(ctx context.Context, t *asynq.Task) error {
+	var p TaskEventPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal task event payload: %w", err)
+	}
+
+	s.server.Logger.Info().
+		Str("event", t.Type()).
+		Str("task_id", p.TaskID).
+		Str("title", p.Title).
+		Msg("Processing task event")
+
+	return nil
+}
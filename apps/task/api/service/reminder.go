@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/goku-m/main/apps/task/api/repository"
+	"github.com/goku-m/main/internal/shared/server"
+	"github.com/goku-m/main/internal/shared/worker"
+)
+
+// ReminderInterval is how often the reminder scheduler looks for overdue tasks.
+const ReminderInterval = time.Minute
+
+// ReminderService periodically reports overdue tasks. It is started and
+// stopped through the task module's lifecycle hooks.
+type ReminderService struct {
+	server   *server.Server
+	taskRepo *repository.TaskRepository
+	worker   *worker.Worker
+}
+
+func NewReminderService(s *server.Server, taskRepo *repository.TaskRepository) *ReminderService {
+	r := &ReminderService{
+		server:   s,
+		taskRepo: taskRepo,
+	}
+	r.worker = worker.Every("reminder scheduler", ReminderInterval, r.remind)
+	return r
+}
+
+// Start launches the scheduler loop, which first runs right away. The context
+// only bounds startup; the loop runs until Stop is called.
+func (r *ReminderService) Start(ctx context.Context) error {
+	return r.worker.Start(ctx)
+}
+
+// Stop cancels the scheduler loop and waits for it to exit or for ctx to expire.
+func (r *ReminderService) Stop(ctx context.Context) error {
+	return r.worker.Stop(ctx)
+}
+
+func (r *ReminderService) remind(ctx context.Context) {
+	tasks, err := r.taskRepo.GetOverdueTasks(ctx, time.Now())
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			r.server.Logger.Error().Err(err).Msg("failed to fetch overdue tasks")
+		}
+		return
+	}
+
+	for _, t := range tasks {
+		r.server.Logger.Info().
+			Str("event", "task_overdue").
+			Str("task_id", t.ID.String()).
+			Str("title", t.Title).
+			Time("due_date", *t.DueDate).
+			Msg("Task is overdue")
+	}
+}
@@ -7,9 +7,10 @@ import (
 )
 
 type Services struct {
-	Auth *AuthService
-	Job  *job.JobService
-	Task *TaskService
+	Auth     *AuthService
+	Job      *job.JobService
+	Task     *TaskService
+	Reminder *ReminderService
 }
 
 func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
@@ -22,9 +23,19 @@ func NewServices(s *server.Server, repos *repository.Repositories) (*Services, e
 	// 	return nil, fmt.Errorf("failed to create AWS client: %w", err)
 	// }
 
+	taskService := NewTaskService(s, repos.Task)
+
+	// The routes command builds modules without a job queue
+	if s.Job != nil {
+		for _, event := range []string{EventTaskCreated, EventTaskUpdated, EventTaskDeleted, EventTaskRestored, EventTaskPurged} {
+			s.Job.Handle(event, taskService.HandleTaskEvent)
+		}
+	}
+
 	return &Services{
-		Job:  s.Job,
-		Auth: authService,
-		Task: NewTaskService(s, repos.Task),
+		Job:      s.Job,
+		Auth:     authService,
+		Task:     taskService,
+		Reminder: NewReminderService(s, repos.Task),
 	}, nil
 }
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
@@ -9,7 +11,9 @@ import (
 	"github.com/goku-m/main/apps/task/api/model"
 	"github.com/goku-m/main/apps/task/api/model/task"
 	"github.com/goku-m/main/apps/task/api/repository"
+	"github.com/goku-m/main/internal/shared/audit"
 	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/outbox"
 	"github.com/goku-m/main/internal/shared/server"
 )
 
@@ -31,7 +35,14 @@ func (s *TaskService) CreateTask(ctx echo.Context, payload *task.CreateTaskPaylo
 
 	// Validate parent task exists and belongs to task (if provided)
 
-	taskItem, err := s.taskRepo.CreateTask(ctx.Request().Context(), payload)
+	var taskItem *task.Task
+	err := s.server.DB.InTx(middleware.AuditContext(ctx), func(txCtx context.Context) error {
+		var err error
+		if taskItem, err = s.taskRepo.CreateTask(txCtx, payload); err != nil {
+			return err
+		}
+		return outbox.Add(txCtx, s.server.DB, taskEvent(EventTaskCreated, taskItem))
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create task")
 		return nil, err
@@ -76,7 +87,14 @@ func (s *TaskService) GetTasks(ctx echo.Context, query *task.GetTasksQuery) (*mo
 func (s *TaskService) UpdateTask(ctx echo.Context, payload *task.UpdateTaskPayload) (*task.Task, error) {
 	logger := middleware.GetLogger(ctx)
 
-	updatedTask, err := s.taskRepo.UpdateTask(ctx.Request().Context(), payload)
+	var updatedTask *task.Task
+	err := s.server.DB.InTx(middleware.AuditContext(ctx), func(txCtx context.Context) error {
+		var err error
+		if updatedTask, err = s.taskRepo.UpdateTask(txCtx, payload); err != nil {
+			return err
+		}
+		return outbox.Add(txCtx, s.server.DB, taskEvent(EventTaskUpdated, updatedTask))
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update task")
 		return nil, err
@@ -98,7 +116,17 @@ func (s *TaskService) UpdateTask(ctx echo.Context, payload *task.UpdateTaskPaylo
 func (s *TaskService) DeleteTask(ctx echo.Context, taskID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.taskRepo.DeleteTask(ctx.Request().Context(), taskID)
+	err := s.server.DB.InTx(middleware.AuditContext(ctx), func(txCtx context.Context) error {
+		if err := s.taskRepo.DeleteTask(txCtx, taskID); err != nil {
+			return err
+		}
+		return outbox.Add(txCtx, s.server.DB, outbox.Message{
+			AggregateType: "task",
+			AggregateID:   taskID.String(),
+			Type:          EventTaskDeleted,
+			Payload:       TaskEventPayload{TaskID: taskID.String()},
+		})
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete task")
 		return err
@@ -113,3 +141,101 @@ func (s *TaskService) DeleteTask(ctx echo.Context, taskID uuid.UUID) error {
 
 	return nil
 }
+
+func (s *TaskService) GetDeletedTasks(ctx echo.Context) ([]task.Task, error) {
+	logger := middleware.GetLogger(ctx)
+
+	tasks, err := s.taskRepo.GetDeletedTasks(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch deleted tasks")
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (s *TaskService) RestoreTask(ctx echo.Context, taskID uuid.UUID) (*task.Task, error) {
+	logger := middleware.GetLogger(ctx)
+
+	var restoredTask *task.Task
+	err := s.server.DB.InTx(middleware.AuditContext(ctx), func(txCtx context.Context) error {
+		var err error
+		if restoredTask, err = s.taskRepo.RestoreTask(txCtx, taskID); err != nil {
+			return err
+		}
+		return outbox.Add(txCtx, s.server.DB, taskEvent(EventTaskRestored, restoredTask))
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to restore task")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "task_restored").
+		Str("task_id", taskID.String()).
+		Msg("Task restored from trash")
+
+	return restoredTask, nil
+}
+
+func (s *TaskService) PurgeTask(ctx echo.Context, taskID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	err := s.server.DB.InTx(middleware.AuditContext(ctx), func(txCtx context.Context) error {
+		if err := s.taskRepo.PurgeTask(txCtx, taskID); err != nil {
+			return err
+		}
+		return outbox.Add(txCtx, s.server.DB, TaskPurgedEvent(taskID))
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to purge task")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "task_purged").
+		Str("task_id", taskID.String()).
+		Msg("Task permanently deleted")
+
+	return nil
+}
+
+// GetTaskHistory returns a page of the audit log of tasks, newest first.
+func (s *TaskService) GetTaskHistory(ctx echo.Context, query *task.GetTaskHistoryQuery) (*model.PaginatedResponse[audit.Entry], error) {
+	logger := middleware.GetLogger(ctx)
+
+	filter := audit.Filter{
+		Entity: "task",
+		From:   query.From,
+		To:     query.To,
+		Page:   *query.Page,
+		Limit:  *query.Limit,
+	}
+	if query.TaskID != nil {
+		filter.EntityID = query.TaskID.String()
+	}
+	if query.Actor != nil {
+		filter.Actor = *query.Actor
+	}
+	if query.Operation != nil {
+		filter.Operation = audit.Operation(*query.Operation)
+	}
+
+	entries, total, err := audit.Query(ctx.Request().Context(), s.server.DB, filter)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch task history")
+		return nil, err
+	}
+
+	return &model.PaginatedResponse[audit.Entry]{
+		Data:       entries,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		Total:      total,
+		TotalPages: (total + filter.Limit - 1) / filter.Limit,
+	}, nil
+}
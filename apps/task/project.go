@@ -1,17 +1,32 @@
 package task
 
 import (
+	"context"
+
 	"github.com/goku-m/main/apps/task/api"
 	"github.com/goku-m/main/apps/task/api/handler"
+	"github.com/goku-m/main/apps/task/api/model/task"
 	"github.com/goku-m/main/apps/task/api/repository"
 	"github.com/goku-m/main/apps/task/api/service"
 	"github.com/goku-m/main/internal/gateway"
+	"github.com/goku-m/main/internal/shared/audit"
+	"github.com/goku-m/main/internal/shared/outbox"
+	"github.com/goku-m/main/internal/shared/routing"
 	"github.com/goku-m/main/internal/shared/server"
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/trash"
 )
 
-// NewRouter builds the Agrifolio app router for the gateway to mount.
-func NewRouter(s *server.Server, h *handler.Handlers) *echo.Echo {
+func init() {
+	gateway.Register(gateway.Factory{
+		Name:   "task",
+		Prefix: "/task",
+		Schema: "task",
+		New:    Module,
+	})
+}
+
+// NewRouter builds the task app router for the gateway to mount.
+func NewRouter(s *server.Server, h *handler.Handlers) *routing.Router {
 	return api.NewRouter(s, h)
 }
 
@@ -22,6 +37,33 @@ func Module(s *server.Server) (gateway.Module, error) {
 		return gateway.Module{}, err
 	}
 
+	// The routes command builds modules without a database or job queue
+	if s.DB != nil && s.Job != nil {
+		relay := outbox.NewRelay(s.DB, s.Job, s.Logger)
+		s.AddHook(server.Hook{
+			Name:    "task outbox relay",
+			OnStart: relay.Start,
+			OnStop:  relay.Stop,
+		})
+	}
+
+	purger := trash.NewPurger(s.DB, "tasks", "task", func(row *task.Task) string { return row.ID.String() }, s.Config.Trash.Retention, s.Logger)
+	purger.OnPurge = func(ctx context.Context, row *task.Task) error {
+		return outbox.Add(ctx, s.DB, service.TaskPurgedEvent(row.ID))
+	}
+	s.AddHook(server.Hook{
+		Name:    "task trash purge",
+		OnStart: purger.Start,
+		OnStop:  purger.Stop,
+	})
+
+	pruner := audit.NewPruner(s.DB, s.Config.Audit.Retention, s.Logger)
+	s.AddHook(server.Hook{
+		Name:    "task audit pruner",
+		OnStart: pruner.Start,
+		OnStop:  pruner.Stop,
+	})
+
 	handlers := api.NewHandlers(s, services)
 	router := api.NewRouter(s, handlers)
 
@@ -29,5 +71,7 @@ func Module(s *server.Server) (gateway.Module, error) {
 		Name:   "task",
 		Prefix: "/task",
 		Router: router,
+		Start:  services.Reminder.Start,
+		Stop:   services.Reminder.Stop,
 	}, nil
 }
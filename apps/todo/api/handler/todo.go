@@ -10,7 +10,10 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/goku-m/main/apps/todo/api/service"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/render"
 	"github.com/goku-m/main/internal/shared/server"
+	"github.com/goku-m/main/internal/shared/urls"
 	"github.com/labstack/echo/v4"
 )
 
@@ -128,7 +131,7 @@ func (h *TodoHandler) CreateTodo(c echo.Context) error {
 	}
 
 	// Redirect back to list (refresh)
-	return c.Redirect(http.StatusSeeOther, "/todo")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "todo.home"))
 }
 
 func (h *TodoHandler) UpdateTodo(c echo.Context) error {
@@ -185,7 +188,7 @@ func (h *TodoHandler) UpdateTodo(c echo.Context) error {
 	}
 
 	// 5) Redirect back (refresh)
-	return c.Redirect(http.StatusSeeOther, "/todo")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "todo.home"))
 }
 
 func (h *TodoHandler) DeleteTodo(c echo.Context) error {
@@ -204,5 +207,65 @@ func (h *TodoHandler) DeleteTodo(c echo.Context) error {
 		return err
 	}
 
-	return c.Redirect(http.StatusSeeOther, "/todo")
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "todo.home"))
+}
+
+func (h *TodoHandler) GetTodoTrashPage(c echo.Context) error {
+	todos, err := h.todoService.GetDeletedTodos(c)
+	if err != nil {
+		return err
+	}
+
+	view := make([]pages.TodoView, 0, len(todos))
+	for _, t := range todos {
+		view = append(view, pages.TodoView{
+			ID:        t.ID.String(),
+			Title:     t.Title,
+			Priority:  string(t.Priority),
+			Status:    string(t.Status),
+			DeletedAt: t.DeletedAt,
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	return pages.Trash(view).Render(c.Request().Context(), c.Response())
+}
+
+func (h *TodoHandler) RestoreTodo(c echo.Context) error {
+	todoID, err := uuid.Parse(c.FormValue("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if _, err := h.todoService.RestoreTodo(c, todoID); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "todo.trash"))
+}
+
+func (h *TodoHandler) PurgeTodo(c echo.Context) error {
+	todoID, err := uuid.Parse(c.FormValue("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.todoService.PurgeTodo(c, todoID); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, urls.Route(c.Request().Context(), "todo.trash"))
+}
+
+// StreamTodoChanges streams inserts, updates and deletes of todos, made by
+// any replica or by hand, as server-sent events.
+func (h *TodoHandler) StreamTodoChanges(c echo.Context) error {
+	if h.server.Listener == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "change feed unavailable")
+	}
+
+	sub := h.server.Listener.Subscribe(database.ChangeChannel("todo", "todos"))
+	defer sub.Close()
+
+	return render.StreamChanges(c, sub)
 }
@@ -32,6 +32,7 @@ type Todo struct {
 	DueDate     *time.Time `json:"dueDate" db:"due_date"`
 	CompletedAt *time.Time `json:"completedAt" db:"completed_at"`
 	SortOrder   int        `json:"sortOrder" db:"sort_order"`
+	DeletedAt   *time.Time `json:"deletedAt" db:"deleted_at"`
 }
 
 type PopulatedTodo struct {
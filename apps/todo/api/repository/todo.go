@@ -9,6 +9,7 @@ import (
 
 	"github.com/goku-m/main/apps/todo/api/model"
 	"github.com/goku-m/main/apps/todo/api/model/todo"
+	"github.com/goku-m/main/internal/shared/audit"
 	"github.com/goku-m/main/internal/shared/errs"
 	"github.com/goku-m/main/internal/shared/server"
 	"github.com/google/uuid"
@@ -23,7 +24,51 @@ func NewTodoRepository(server *server.Server) *TodoRepository {
 	return &TodoRepository{server: server}
 }
 
+// audit records a change of a todo in the audit log. Writes call it in the
+// transaction making the change, so the entry commits with it.
+func (r *TodoRepository) audit(ctx context.Context, op audit.Operation, todoID uuid.UUID, before, after *todo.Todo) error {
+	return audit.Record(ctx, r.server.DB, op, "todo", todoID.String(), before, after)
+}
+
+// lockTodo reads a todo, in the trash or not, and locks it until the
+// transaction ends, to audit what a write changes. It returns nil if there
+// is no such todo.
+func (r *TodoRepository) lockTodo(ctx context.Context, todoID uuid.UUID) (*todo.Todo, error) {
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, `SELECT * FROM todos WHERE id=@id FOR UPDATE`, pgx.NamedArgs{
+		"id": todoID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock todo for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	return &todoItem, nil
+}
+
 func (r *TodoRepository) CreateTodo(ctx context.Context, payload *todo.CreateTodoPayload) (*todo.Todo, error) {
+	var created *todo.Todo
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = r.createTodo(ctx, payload); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationCreate, created.ID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *TodoRepository) createTodo(ctx context.Context, payload *todo.CreateTodoPayload) (*todo.Todo, error) {
 	stmt := `
 		INSERT INTO
 			todos (
@@ -48,7 +93,7 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, payload *todo.CreateTod
 		priority = *payload.Priority
 	}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"title":       payload.Title,
 		"description": payload.Description,
 		"priority":    priority,
@@ -76,12 +121,13 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, todoID uuid.UUID) (*to
 	
 	WHERE
 		u.id=@id
+		AND u.deleted_at IS NULL
 	GROUP BY
 		u.id
 		
 `
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"id": todoID,
 	})
 	if err != nil {
@@ -90,7 +136,7 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, todoID uuid.UUID) (*to
 
 	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s todo_id=%s: %w", todoID.String(), err)
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID.String(), err)
 	}
 
 	return &todoItem, nil
@@ -104,9 +150,10 @@ func (r *TodoRepository) CheckTodoExists(ctx context.Context, todoID uuid.UUID)
 			todos
 		WHERE
 			id=@id
+			AND deleted_at IS NULL
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"id": todoID,
 	})
 	if err != nil {
@@ -134,7 +181,7 @@ func (r *TodoRepository) GetTodos(
 	`
 
 	args := pgx.NamedArgs{}
-	conditions := []string{}
+	conditions := []string{"t.deleted_at IS NULL"}
 
 	if query != nil {
 		if query.Status != nil {
@@ -161,21 +208,16 @@ func (r *TodoRepository) GetTodos(
 		}
 	}
 
-	if len(conditions) > 0 {
-		stmt += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	stmt += " WHERE " + strings.Join(conditions, " AND ")
 
 	// ----- count query -----
-	countStmt := "SELECT COUNT(*) FROM todos t"
-	if len(conditions) > 0 {
-		countStmt += " WHERE " + strings.Join(conditions, " AND ")
-	}
+	countStmt := "SELECT COUNT(*) FROM todos t WHERE " + strings.Join(conditions, " AND ")
 
 	// If server/DB/Pool can be nil in your wiring, you may also want to guard here:
 	// if r == nil || r.server == nil || r.server.DB == nil || r.server.DB.Pool == nil { ... }
 
 	var total int
-	err := r.server.DB.Pool.QueryRow(ctx, countStmt, args).Scan(&total)
+	err := r.server.DB.Reader(ctx).QueryRow(ctx, countStmt, args).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count for todos: %w", err)
 	}
@@ -228,7 +270,7 @@ func (r *TodoRepository) GetTodos(
 	args["limit"] = limit
 	args["offset"] = (page - 1) * limit
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute get todos query: %w", err)
 	}
@@ -260,6 +302,25 @@ func (r *TodoRepository) GetTodos(
 }
 
 func (r *TodoRepository) UpdateTodo(ctx context.Context, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
+	var updated *todo.Todo
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTodo(ctx, payload.ID)
+		if err != nil {
+			return err
+		}
+		if updated, err = r.updateTodo(ctx, payload); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationUpdate, updated.ID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *TodoRepository) updateTodo(ctx context.Context, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
 	stmt := "UPDATE todos SET "
 	args := pgx.NamedArgs{
 		"todo_id": payload.ID,
@@ -299,9 +360,9 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, payload *todo.UpdateTod
 	}
 
 	stmt += strings.Join(setClauses, ", ")
-	stmt += " WHERE id = @todo_id  RETURNING *"
+	stmt += " WHERE id = @todo_id AND deleted_at IS NULL RETURNING *"
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -314,24 +375,165 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, payload *todo.UpdateTod
 	return &updatedTodo, nil
 }
 
+// DeleteTodo moves the todo to the trash.
 func (r *TodoRepository) DeleteTodo(ctx context.Context, todoID uuid.UUID) error {
+	return r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		deleted, err := r.deleteTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationDelete, todoID, before, deleted)
+	})
+}
+
+func (r *TodoRepository) deleteTodo(ctx context.Context, todoID uuid.UUID) (*todo.Todo, error) {
+	stmt := `
+		UPDATE todos
+		SET
+			deleted_at = CURRENT_TIMESTAMP
+		WHERE
+			id=@todo_id
+			AND deleted_at IS NULL
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id": todoID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TODO_NOT_FOUND"
+			return nil, errs.NewNotFoundError("todo not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	return &todoItem, nil
+}
+
+// GetDeletedTodos lists the todos in the trash, most recently deleted first.
+func (r *TodoRepository) GetDeletedTodos(ctx context.Context) ([]todo.Todo, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todos
+		WHERE
+			deleted_at IS NOT NULL
+		ORDER BY
+			deleted_at DESC
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get deleted todos query: %w", err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// RestoreTodo takes the todo out of the trash.
+func (r *TodoRepository) RestoreTodo(ctx context.Context, todoID uuid.UUID) (*todo.Todo, error) {
+	var restored *todo.Todo
+	err := r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		if restored, err = r.restoreTodo(ctx, todoID); err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationRestore, todoID, before, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (r *TodoRepository) restoreTodo(ctx context.Context, todoID uuid.UUID) (*todo.Todo, error) {
+	stmt := `
+		UPDATE todos
+		SET
+			deleted_at = NULL
+		WHERE
+			id=@todo_id
+			AND deleted_at IS NOT NULL
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id": todoID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute restore todo query for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TODO_NOT_FOUND"
+			return nil, errs.NewNotFoundError("todo not found in trash", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID.String(), err)
+	}
+
+	return &todoItem, nil
+}
+
+// PurgeTodo permanently deletes a todo in the trash.
+func (r *TodoRepository) PurgeTodo(ctx context.Context, todoID uuid.UUID) error {
+	return r.server.DB.InTx(ctx, func(ctx context.Context) error {
+		purged, err := r.purgeTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		return r.audit(ctx, audit.OperationPurge, todoID, purged, nil)
+	})
+}
+
+func (r *TodoRepository) purgeTodo(ctx context.Context, todoID uuid.UUID) (*todo.Todo, error) {
 	stmt := `
 		DELETE FROM todos
 		WHERE
 			id=@todo_id
+			AND deleted_at IS NOT NULL
+		RETURNING
+		*
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Writer(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"todo_id": todoID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		code := "TODO_NOT_FOUND"
-		return errs.NewNotFoundError("todo not found", false, &code)
+	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TODO_NOT_FOUND"
+			return nil, errs.NewNotFoundError("todo not found in trash", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s: %w", todoID.String(), err)
 	}
 
-	return nil
+	return &todoItem, nil
 }
@@ -3,14 +3,14 @@ package router
 import (
 	"github.com/goku-m/main/apps/todo/api/handler"
 	"github.com/goku-m/main/internal/shared/middleware"
-
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/routing"
 )
 
-func registerPagesRoutes(r *echo.Echo, h *handler.Handlers, auth *middleware.AuthMiddleware) {
+func registerPagesRoutes(r *routing.Router, h *handler.Handlers, auth *middleware.AuthMiddleware) {
 
-	r.GET("/", h.Todo.GetTodoPage)
-	r.GET("/create", h.Todo.CreateTodoPage)
+	r.GET("/", h.Todo.GetTodoPage).Name = "todo.home"
+	r.GET("/create", h.Todo.CreateTodoPage).Name = "todo.create"
 	r.Use(auth.RequireAuthIP)
-	r.GET("/update/:id", h.Todo.UpdateTodoPage)
+	r.GET("/update/:id", h.Todo.UpdateTodoPage).Name = "todo.edit"
+	r.GET("/trash", h.Todo.GetTodoTrashPage).Name = "todo.trash"
 }
@@ -3,17 +3,22 @@ package router
 import (
 	"github.com/goku-m/main/apps/todo/api/handler"
 	"github.com/goku-m/main/internal/shared/middleware"
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/routing"
 )
 
-func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, auth *middleware.AuthMiddleware) {
+func registerTodoRoutes(r *routing.Group, h *handler.TodoHandler, auth *middleware.AuthMiddleware) {
 	// User operations
 	todos := r.Group("/todos")
 	// todos.Use(auth.RequireAuthIP)
 
 	// Collection operations for pages
-	todos.POST("/create", h.CreateTodo)
-	todos.POST("/delete", h.DeleteTodo)
-	todos.POST("/update/:id", h.UpdateTodo)
+	todos.POST("/create", h.CreateTodo).Name = "todo.api.create"
+	todos.POST("/delete", h.DeleteTodo).Name = "todo.api.delete"
+	todos.POST("/update/:id", h.UpdateTodo).Name = "todo.api.update"
+	todos.POST("/restore", h.RestoreTodo).Name = "todo.api.restore"
+	todos.POST("/purge", h.PurgeTodo).Name = "todo.api.purge"
+
+	// Server-sent events for every change to todos
+	todos.GET("/changes", h.StreamTodoChanges).Name = "todo.api.changes"
 
 }
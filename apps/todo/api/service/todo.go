@@ -31,7 +31,7 @@ func (s *TodoService) CreateTodo(ctx echo.Context, payload *todo.CreateTodoPaylo
 
 	// Validate parent todo exists and belongs to todo (if provided)
 
-	todoItem, err := s.todoRepo.CreateTodo(ctx.Request().Context(), payload)
+	todoItem, err := s.todoRepo.CreateTodo(middleware.AuditContext(ctx), payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create todo")
 		return nil, err
@@ -76,7 +76,7 @@ func (s *TodoService) GetTodos(ctx echo.Context, query *todo.GetTodosQuery) (*mo
 func (s *TodoService) UpdateTodo(ctx echo.Context, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
 	logger := middleware.GetLogger(ctx)
 
-	updatedTodo, err := s.todoRepo.UpdateTodo(ctx.Request().Context(), payload)
+	updatedTodo, err := s.todoRepo.UpdateTodo(middleware.AuditContext(ctx), payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update todo")
 		return nil, err
@@ -98,7 +98,7 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, payload *todo.UpdateTodoPaylo
 func (s *TodoService) DeleteTodo(ctx echo.Context, todoID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.todoRepo.DeleteTodo(ctx.Request().Context(), todoID)
+	err := s.todoRepo.DeleteTodo(middleware.AuditContext(ctx), todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete todo")
 		return err
@@ -113,3 +113,53 @@ func (s *TodoService) DeleteTodo(ctx echo.Context, todoID uuid.UUID) error {
 
 	return nil
 }
+
+func (s *TodoService) GetDeletedTodos(ctx echo.Context) ([]todo.Todo, error) {
+	logger := middleware.GetLogger(ctx)
+
+	todos, err := s.todoRepo.GetDeletedTodos(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch deleted todos")
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+func (s *TodoService) RestoreTodo(ctx echo.Context, todoID uuid.UUID) (*todo.Todo, error) {
+	logger := middleware.GetLogger(ctx)
+
+	restoredTodo, err := s.todoRepo.RestoreTodo(middleware.AuditContext(ctx), todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to restore todo")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_restored").
+		Str("todo_id", todoID.String()).
+		Msg("Todo restored from trash")
+
+	return restoredTodo, nil
+}
+
+func (s *TodoService) PurgeTodo(ctx echo.Context, todoID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	err := s.todoRepo.PurgeTodo(middleware.AuditContext(ctx), todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to purge todo")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_purged").
+		Str("todo_id", todoID.String()).
+		Msg("Todo permanently deleted")
+
+	return nil
+}
@@ -3,15 +3,27 @@ package todo
 import (
 	"github.com/goku-m/main/apps/todo/api"
 	"github.com/goku-m/main/apps/todo/api/handler"
+	"github.com/goku-m/main/apps/todo/api/model/todo"
 	"github.com/goku-m/main/apps/todo/api/repository"
 	"github.com/goku-m/main/apps/todo/api/service"
 	"github.com/goku-m/main/internal/gateway"
+	"github.com/goku-m/main/internal/shared/audit"
+	"github.com/goku-m/main/internal/shared/routing"
 	"github.com/goku-m/main/internal/shared/server"
-	"github.com/labstack/echo/v4"
+	"github.com/goku-m/main/internal/shared/trash"
 )
 
-// NewRouter builds the Agrifolio app router for the gateway to mount.
-func NewRouter(s *server.Server, h *handler.Handlers) *echo.Echo {
+func init() {
+	gateway.Register(gateway.Factory{
+		Name:   "todo",
+		Prefix: "/todo",
+		Schema: "todo",
+		New:    Module,
+	})
+}
+
+// NewRouter builds the todo app router for the gateway to mount.
+func NewRouter(s *server.Server, h *handler.Handlers) *routing.Router {
 	return api.NewRouter(s, h)
 }
 
@@ -22,6 +34,20 @@ func Module(s *server.Server) (gateway.Module, error) {
 		return gateway.Module{}, err
 	}
 
+	purger := trash.NewPurger(s.DB, "todos", "todo", func(row *todo.Todo) string { return row.ID.String() }, s.Config.Trash.Retention, s.Logger)
+	s.AddHook(server.Hook{
+		Name:    "todo trash purge",
+		OnStart: purger.Start,
+		OnStop:  purger.Stop,
+	})
+
+	pruner := audit.NewPruner(s.DB, s.Config.Audit.Retention, s.Logger)
+	s.AddHook(server.Hook{
+		Name:    "todo audit pruner",
+		OnStart: pruner.Start,
+		OnStop:  pruner.Stop,
+	})
+
 	handlers := api.NewHandlers(s, services)
 	router := api.NewRouter(s, handlers)
 
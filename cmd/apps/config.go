@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/goku-m/main/internal/shared/config"
+)
+
+// errInvalidConfig signals that the problems were already printed.
+var errInvalidConfig = errors.New("config check failed")
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: apps config check [--format table|json]")
+	}
+	return runConfigCheck(args[1:])
+}
+
+// runConfigCheck loads and validates the config the way the server would and
+// prints the resolved values with secrets redacted. It fails with every
+// validation problem, so deployments can run it before rolling out.
+func runConfigCheck(args []string) error {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	cfg, err := config.LoadConfig()
+
+	var verr *config.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		if *format == "json" {
+			if err := writeJSON(verr); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, verr)
+		}
+		return errInvalidConfig
+	case err != nil:
+		return err
+	}
+
+	entries := cfg.Entries()
+	if *format == "json" {
+		return writeJSON(entries)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, e := range entries {
+		source := e.Source
+		if source == "" {
+			source = "default"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Key, e.Value, source)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -3,26 +3,66 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
-	"github.com/goku-m/main/apps/task"
-	"github.com/goku-m/main/apps/todo"
+	_ "github.com/goku-m/main/apps"
 	"github.com/goku-m/main/internal/gateway"
 	"github.com/goku-m/main/internal/shared/config"
 	"github.com/goku-m/main/internal/shared/database"
 	"github.com/goku-m/main/internal/shared/logger"
+	"github.com/goku-m/main/internal/shared/middleware"
 	"github.com/goku-m/main/internal/shared/server"
+	"github.com/goku-m/main/internal/shared/urls"
 )
 
 const DefaultContextTimeout = 30
 
+const usage = `usage: apps [command]
+
+commands:
+  (none)    start the gateway server
+  routes    list mounted modules and their routes (--format table|json)
+  config check
+            validate the config and print it with secrets redacted
+            (--format table|json)
+`
+
 func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "routes":
+			err = runRoutes(os.Args[2:])
+		case "config":
+			err = runConfig(os.Args[2:])
+		case "help", "-h", "--help":
+			fmt.Print(usage)
+		default:
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		if errors.Is(err, errInvalidConfig) {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	serve()
+}
+
+func serve() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		panic("failed to load config: " + err.Error())
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		os.Exit(1)
 	}
 
 	log := logger.NewLogger(cfg.Observability)
@@ -39,23 +79,41 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to initialize server")
 	}
 
-	todoModule, moduleErr := todo.Module(srv)
-	if moduleErr != nil {
-		log.Fatal().Err(moduleErr).Msg("could not initialize agrifolio module")
+	srv.Reloader.Subscribe("logger", func(_, current *config.Config) {
+		logger.SetLevel(logger.ParseLevel(current.Observability.GetLogLevel()))
+	})
+
+	modules, err := gateway.Build(srv, cfg.Gateway)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not initialize modules")
 	}
 
-	taskModule, moduleErr := task.Module(srv)
-	if moduleErr != nil {
-		log.Fatal().Err(moduleErr).Msg("could not initialize agrifolio module")
+	// Initialize gateway router with the shared edge middleware pipeline
+	middlewares := middleware.NewMiddlewares(srv)
+	pipeline := gateway.DefaultPipeline(srv, middlewares)
+	trusted, err := urls.ParseTrustedProxies(cfg.Gateway.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid gateway.trusted_proxies")
+	}
+	r := gateway.New(pipeline, trusted, modules...)
+	r.HTTPErrorHandler = middlewares.Global.GlobalErrorHandler
+
+	if cfg.Gateway.AdminEnabled {
+		gateway.RegisterAdminRoutes(r, gateway.Describe(pipeline, modules...))
+		gateway.RegisterReloadRoute(r, func() (*config.ReloadResult, error) {
+			return reloadConfig(srv)
+		})
 	}
 
-	// Initialize gateway router
-	r := gateway.New(todoModule, taskModule)
+	if cfg.Gateway.MetricsEnabled {
+		gateway.RegisterMetricsRoute(r, srv)
+	}
 
 	// Setup HTTP server
 	srv.SetupHTTPServer(r)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	reloadOnSIGHUP(ctx, srv)
 
 	// Start server
 	go func() {
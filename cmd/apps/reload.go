@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/server"
+)
+
+// reloadConfig re-reads the config and logs what was applied and what still
+// needs a restart.
+func reloadConfig(srv *server.Server) (*config.ReloadResult, error) {
+	result, err := srv.Reloader.Reload()
+	if err != nil {
+		srv.Logger.Error().Err(err).Msg("config reload failed, keeping current config")
+		return nil, err
+	}
+
+	for _, change := range result.Applied {
+		srv.Logger.Info().
+			Str("key", change.Key).
+			Str("old", change.Old).
+			Str("new", change.New).
+			Msg("config change applied")
+	}
+	for _, change := range result.RestartRequired {
+		srv.Logger.Warn().
+			Str("key", change.Key).
+			Str("old", change.Old).
+			Str("new", change.New).
+			Msg("config change requires a restart")
+	}
+
+	srv.Logger.Info().
+		Int("applied", len(result.Applied)).
+		Int("restart_required", len(result.RestartRequired)).
+		Msg("config reloaded")
+
+	return result, nil
+}
+
+// reloadOnSIGHUP reloads the config on every SIGHUP until ctx is done.
+func reloadOnSIGHUP(ctx context.Context, srv *server.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				_, _ = reloadConfig(srv)
+			}
+		}
+	}()
+}
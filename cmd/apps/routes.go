@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/goku-m/main/internal/gateway"
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/logger"
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/server"
+	"github.com/rs/zerolog"
+)
+
+// runRoutes prints the modules the gateway would mount with the current
+// config. Module routers are built without connecting to Postgres or Redis,
+// so the command works wherever the config loads.
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	log := logger.NewLogger(cfg.Observability).Level(zerolog.WarnLevel)
+	srv := &server.Server{Config: cfg, Logger: &log}
+
+	modules, err := gateway.Build(srv, cfg.Gateway)
+	if err != nil {
+		return err
+	}
+
+	pipeline := gateway.DefaultPipeline(srv, middleware.NewMiddlewares(srv))
+	infos := gateway.Describe(pipeline, modules...)
+
+	switch *format {
+	case "json":
+		return writeJSON(infos)
+	case "table":
+		return gateway.WriteTable(os.Stdout, infos)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
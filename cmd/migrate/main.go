@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  status    show the applied and latest version of each project
+  up        apply every pending migration
+  down [N]  roll back the last N migrations (default 1), needs --project
+  goto V    migrate up or down to version V, needs --project
+
+flags:
+  --project name  only migrate this project (task, todo, ...)
+  --dsn url       connect to this database instead of the configured one
+  --dry-run       print the SQL that would run instead of running it
+  --format        status output: table or json
+`
+
+// options are the flags shared by every command.
+type options struct {
+	project string
+	dsn     string
+	dryRun  bool
+	format  string
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := args[0]
+	if command == "help" || command == "-h" || command == "--help" {
+		fmt.Print(usage)
+		return nil
+	}
+
+	opts, positional, err := parseFlags(command, args[1:])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	log := logger.NewLogger(cfg.Observability).Level(zerolog.InfoLevel)
+
+	projects, err := selectProjects(opts.project)
+	if err != nil {
+		return err
+	}
+
+	// target computes the version to move a project to from its current one.
+	var target func(m *database.Migrator, current int32) int32
+	switch command {
+	case "status":
+		if len(positional) > 0 {
+			return fmt.Errorf("status takes no arguments")
+		}
+	case "up":
+		if len(positional) > 0 {
+			return fmt.Errorf("up takes no arguments")
+		}
+		target = func(m *database.Migrator, _ int32) int32 { return m.Latest() }
+	case "down":
+		n, err := versionArg(positional, "1")
+		if err != nil {
+			return fmt.Errorf("down: %w", err)
+		}
+		if n < 1 {
+			return fmt.Errorf("down: N must be at least 1")
+		}
+		target = func(_ *database.Migrator, current int32) int32 { return current - n }
+	case "goto":
+		if len(positional) == 0 {
+			return fmt.Errorf("goto: missing version")
+		}
+		v, err := versionArg(positional, "")
+		if err != nil {
+			return fmt.Errorf("goto: %w", err)
+		}
+		target = func(*database.Migrator, int32) int32 { return v }
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Rolling back is per app: never let it touch every schema at once.
+	if (command == "down" || command == "goto") && len(projects) > 1 {
+		return fmt.Errorf("%s needs --project, one of: %s", command, projectNames(projects))
+	}
+
+	var conn *pgx.Conn
+	if opts.dsn != "" {
+		conn, err = pgx.Connect(ctx, opts.dsn)
+	} else {
+		conn, err = database.Connect(ctx, &log, cfg.Database)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %s", cfg.Redact(err.Error()))
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if command == "status" {
+		return runStatus(ctx, conn, projects, opts.format)
+	}
+
+	for _, project := range projects {
+		m, err := database.NewMigrator(conn, project)
+		if err != nil {
+			return err
+		}
+		current, err := m.CurrentVersion(ctx)
+		if err != nil {
+			return err
+		}
+		to := target(m, current)
+
+		if opts.dryRun {
+			// A real run would stop here too
+			drifts, _, err := m.Drifts(ctx, current)
+			if err != nil {
+				return err
+			}
+			if len(drifts) > 0 {
+				return &database.DriftError{Project: project.Name, Drifts: drifts}
+			}
+
+			steps, err := m.Plan(ctx, to)
+			if err != nil {
+				return err
+			}
+			printPlan(project, current, to, steps)
+			continue
+		}
+
+		// The lock is taken inside MigrateTo, so report from the version it
+		// actually started at.
+		current, err = m.MigrateTo(ctx, &log, to)
+		if err != nil {
+			return fmt.Errorf("%s: %w", project.Name, err)
+		}
+		if current == to {
+			log.Info().Msgf("database schema up to date for %s, version %d", project.SchemaTable, to)
+		} else {
+			log.Info().Msgf("migrated database schema for %s, from %d to %d", project.SchemaTable, current, to)
+		}
+	}
+	return nil
+}
+
+// parseFlags accepts flags before, between and after the positional
+// arguments, so "down 2 --project task" works like "down --project task 2".
+func parseFlags(command string, args []string) (options, []string, error) {
+	var opts options
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&opts.project, "project", "", "only migrate this project")
+	fs.StringVar(&opts.dsn, "dsn", "", "connect to this database instead of the configured one")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "print the SQL instead of running it")
+	fs.StringVar(&opts.format, "format", "table", "status output format: table or json")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return opts, nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if opts.format != "table" && opts.format != "json" {
+		return opts, nil, fmt.Errorf("unknown format %q", opts.format)
+	}
+	return opts, positional, nil
+}
+
+func versionArg(positional []string, fallback string) (int32, error) {
+	if len(positional) > 1 {
+		return 0, fmt.Errorf("too many arguments")
+	}
+	arg := fallback
+	if len(positional) == 1 {
+		arg = positional[0]
+	}
+	v, err := strconv.ParseInt(arg, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", arg)
+	}
+	return int32(v), nil
+}
+
+// selectProjects returns the projects to act on: all of them, or the one
+// named by --project.
+func selectProjects(name string) ([]database.MigrationProject, error) {
+	projects, err := database.MigrationProjects()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return projects, nil
+	}
+
+	for _, project := range projects {
+		if project.Name == name {
+			return []database.MigrationProject{project}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown project %q, expected one of: %s", name, projectNames(projects))
+}
+
+func projectNames(projects []database.MigrationProject) string {
+	names := make([]string, len(projects))
+	for i, project := range projects {
+		names[i] = project.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func runStatus(ctx context.Context, conn *pgx.Conn, projects []database.MigrationProject, format string) error {
+	var statuses []database.MigrationStatus
+	for _, project := range projects {
+		m, err := database.NewMigrator(conn, project)
+		if err != nil {
+			return err
+		}
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tTABLE\tCURRENT\tLATEST\tPENDING\tDRIFTED")
+	for _, s := range statuses {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\n", s.Name, s.SchemaTable, s.Current, s.Latest, list(s.Pending), list(s.Drifted))
+	}
+	return tw.Flush()
+}
+
+func list(names []string) string {
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func printPlan(project database.MigrationProject, from, to int32, steps []database.MigrationStep) {
+	if len(steps) == 0 {
+		fmt.Printf("-- %s: at version %d, nothing to do\n\n", project.Name, from)
+		return
+	}
+
+	fmt.Printf("-- %s: version %d to %d\n\n", project.Name, from, to)
+	for _, step := range steps {
+		fmt.Printf("-- %s %s (version %d)\n%s\n\n", step.Direction, step.Name, step.Version, strings.TrimSpace(step.SQL))
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/logger"
+	"github.com/rs/zerolog"
+)
+
+const usage = `usage: seed [flags]
+
+Loads the seed files of internal/shared/database/seeds/<project>/<env> into
+the configured database. Seeds are idempotent, so running them again is safe.
+
+flags:
+`
+
+// productionEnv is never seeded: seeds are sample data.
+const productionEnv = "production"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	env := fs.String("env", "", "seed environment: local, test or demo (default primary.env)")
+	project := fs.String("project", "", "comma-separated projects to seed (default all)")
+	list := fs.Bool("list", false, "list the seed files instead of loading them")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if *env == "" {
+		*env = cfg.Primary.Env
+	}
+	if *env == productionEnv || cfg.Primary.Env == productionEnv {
+		return fmt.Errorf("refusing to seed a %s database", productionEnv)
+	}
+
+	var projects []string
+	if *project != "" {
+		projects = strings.Split(*project, ",")
+	}
+
+	files, err := database.SeedFiles(*env, projects...)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, f := range files {
+			fmt.Printf("%s/%s/%s\n", f.Project, f.Environment, f.Name)
+		}
+		return nil
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no seed files for environment %q", *env)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log := logger.NewLogger(cfg.Observability).Level(zerolog.InfoLevel)
+
+	conn, err := database.Connect(ctx, &log, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %s", cfg.Redact(err.Error()))
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	return database.Seed(ctx, &log, conn, *env, projects...)
+}
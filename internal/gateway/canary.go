@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// VariantHeader pins a request to a named variant of a canary group,
+	// which is handy for testing a variant before it receives any weight.
+	VariantHeader = "X-Gateway-Variant"
+	// VariantCookie keeps a client on the variant it was first assigned.
+	VariantCookie = "gateway_variant"
+
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// canary splits the traffic of modules that share the same match rules
+// between them according to their weights.
+type canary struct {
+	variants []*route
+	weights  []int
+	total    int
+	// intn returns a number in [0, n) to assign new clients by weight.
+	intn func(n int) int
+}
+
+func newCanary(variants []*route) *canary {
+	cn := &canary{variants: variants, weights: make([]int, len(variants)), intn: rand.IntN}
+	for i, rt := range variants {
+		cn.weights[i] = max(rt.module.Weight, 0)
+		cn.total += cn.weights[i]
+	}
+
+	// Without any weights the variants split the traffic evenly.
+	if cn.total == 0 {
+		for i := range cn.weights {
+			cn.weights[i] = 1
+		}
+		cn.total = len(cn.weights)
+	}
+
+	return cn
+}
+
+// pick selects the variant serving r. A variant named by VariantHeader always
+// wins; otherwise the variant from VariantCookie is kept as long as it still
+// receives traffic. New clients are assigned by weight, in which case assigned
+// is true and the caller should persist the choice in the cookie.
+func (cn *canary) pick(r *http.Request) (rt *route, assigned bool) {
+	if i := cn.index(r.Header.Get(VariantHeader)); i >= 0 {
+		return cn.variants[i], false
+	}
+
+	if ck, err := r.Cookie(VariantCookie); err == nil {
+		if i := cn.index(ck.Value); i >= 0 && cn.weights[i] > 0 {
+			return cn.variants[i], false
+		}
+	}
+
+	n := cn.intn(cn.total)
+	for i, w := range cn.weights {
+		if n -= w; n < 0 {
+			return cn.variants[i], true
+		}
+	}
+	return cn.variants[len(cn.variants)-1], true
+}
+
+func (cn *canary) index(variant string) int {
+	if variant == "" {
+		return -1
+	}
+	return slices.IndexFunc(cn.variants, func(rt *route) bool {
+		return rt.variant == variant
+	})
+}
+
+// variantCookie remembers the assigned variant for every path under prefix.
+func variantCookie(prefix, variant string) *http.Cookie {
+	return &http.Cookie{
+		Name:     VariantCookie,
+		Value:    variant,
+		Path:     prefix,
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// groupVariants folds routes with identical match rules into canary groups.
+// The first route of each group stays in place and carries the group; the
+// other members are removed from the list.
+func groupVariants(routes []*route) []*route {
+	var (
+		out    []*route
+		groups = make(map[string][]*route)
+	)
+	for _, rt := range routes {
+		key := rt.matchKey()
+		if _, ok := groups[key]; !ok {
+			out = append(out, rt)
+		}
+		groups[key] = append(groups[key], rt)
+	}
+
+	for _, rt := range out {
+		if variants := groups[rt.matchKey()]; len(variants) > 1 {
+			rt.canary = newCanary(variants)
+		}
+	}
+
+	return out
+}
+
+// matchKey identifies the requests a route matches, so routes that would
+// shadow each other end up in the same canary group.
+func (rt *route) matchKey() string {
+	var b strings.Builder
+	b.WriteString(rt.prefix)
+
+	hosts := slices.Clone(rt.module.Hosts)
+	for i, h := range hosts {
+		hosts[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	slices.Sort(hosts)
+	for _, h := range hosts {
+		b.WriteString("|host=" + h)
+	}
+
+	headers := make([]string, 0, len(rt.headers))
+	for name, value := range rt.headers {
+		headers = append(headers, http.CanonicalHeaderKey(name)+":"+value)
+	}
+	slices.Sort(headers)
+	for _, h := range headers {
+		b.WriteString("|header=" + h)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestCanary groups one module per variant under /m, with the given
+// weights, and assigns new clients with a chooser that always returns n.
+func newTestCanary(t *testing.T, n int, weights map[string]int, order ...string) *canary {
+	t.Helper()
+
+	var routes []*route
+	for _, variant := range order {
+		routes = append(routes, newRoute(Module{
+			Name:    "m",
+			Prefix:  "/m",
+			Variant: variant,
+			Weight:  weights[variant],
+			Router:  http.NotFoundHandler(),
+		}))
+	}
+
+	grouped := groupVariants(routes)
+	if len(grouped) != 1 || grouped[0].canary == nil {
+		t.Fatalf("variants were not grouped: %d routes", len(grouped))
+	}
+
+	cn := grouped[0].canary
+	cn.intn = func(total int) int {
+		if n >= total {
+			t.Fatalf("chooser asked for n=%d with total %d", n, total)
+		}
+		return n
+	}
+	return cn
+}
+
+func TestCanaryPick(t *testing.T) {
+	tests := []struct {
+		name         string
+		weights      map[string]int
+		n            int
+		cookie       string
+		header       string
+		wantVariant  string
+		wantAssigned bool
+	}{
+		{"weight 0 never assigned", map[string]int{"stable": 0, "canary": 100}, 0, "", "", "canary", true},
+		{"weight 100 takes everything", map[string]int{"stable": 0, "canary": 100}, 99, "", "", "canary", true},
+		{"weighted low end", map[string]int{"stable": 70, "canary": 30}, 69, "", "", "stable", true},
+		{"weighted high end", map[string]int{"stable": 70, "canary": 30}, 70, "", "", "canary", true},
+		{"no weights split evenly", map[string]int{}, 1, "", "", "canary", true},
+		{"sticky cookie wins over weights", map[string]int{"stable": 1, "canary": 99}, 50, "stable", "", "stable", false},
+		{"cookie for zero weight variant is reassigned", map[string]int{"stable": 0, "canary": 100}, 0, "stable", "", "canary", true},
+		{"unknown variant in cookie is reassigned", map[string]int{"stable": 70, "canary": 30}, 0, "gone", "", "stable", true},
+		{"header pins zero weight variant", map[string]int{"stable": 100, "canary": 0}, 0, "", "canary", "canary", false},
+		{"header wins over cookie", map[string]int{"stable": 50, "canary": 50}, 0, "stable", "canary", "canary", false},
+		{"unknown header falls back to cookie", map[string]int{"stable": 50, "canary": 50}, 99, "stable", "gone", "stable", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn := newTestCanary(t, tt.n, tt.weights, "stable", "canary")
+
+			req := httptest.NewRequest(http.MethodGet, "/m/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: VariantCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(VariantHeader, tt.header)
+			}
+
+			rt, assigned := cn.pick(req)
+			if rt.variant != tt.wantVariant || assigned != tt.wantAssigned {
+				t.Errorf("pick = (%s, %v), want (%s, %v)", rt.variant, assigned, tt.wantVariant, tt.wantAssigned)
+			}
+		})
+	}
+}
+
+func TestGroupVariantsByMatchKey(t *testing.T) {
+	handler := http.NotFoundHandler()
+	routes := []*route{
+		newRoute(Module{Name: "a", Prefix: "/m", Hosts: []string{"x.local", "Y.local"}, Headers: map[string]string{"x-beta": "1"}, Router: handler}),
+		newRoute(Module{Name: "b", Prefix: "/m/", Hosts: []string{"y.local", "x.local"}, Headers: map[string]string{"X-Beta": "1"}, Router: handler}),
+		newRoute(Module{Name: "c", Prefix: "/m", Hosts: []string{"x.local"}, Router: handler}),
+		newRoute(Module{Name: "d", Prefix: "/other", Router: handler}),
+	}
+
+	grouped := groupVariants(routes)
+	if len(grouped) != 3 {
+		t.Fatalf("got %d routes, want 3", len(grouped))
+	}
+
+	if cn := grouped[0].canary; cn == nil || len(cn.variants) != 2 ||
+		cn.variants[0].module.Name != "a" || cn.variants[1].module.Name != "b" {
+		t.Errorf("a and b were not grouped in order: %+v", grouped[0].canary)
+	}
+	for _, rt := range grouped[1:] {
+		if rt.canary != nil {
+			t.Errorf("%s should not be a canary group", rt.module.Name)
+		}
+	}
+}
+
+func TestCanaryCookieSetOnAssignment(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	gw := New(nil, nil,
+		Module{Name: "m", Prefix: "/m", Variant: "stable", Weight: 100, Router: handler},
+		Module{Name: "m", Prefix: "/m", Variant: "canary", Weight: 0, Router: handler},
+	)
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/m/x", nil))
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != VariantCookie || cookies[0].Value != "stable" || cookies[0].Path != "/m" {
+		t.Fatalf("cookies = %v, want %s=stable for /m", cookies, VariantCookie)
+	}
+
+	// A client that already carries the cookie is not assigned again
+	req := httptest.NewRequest(http.MethodGet, "/m/x", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	if got := rec.Result().Cookies(); len(got) != 0 {
+		t.Errorf("sticky client got cookies %v", got)
+	}
+}
@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/goku-m/main/internal/shared/routing"
+	"github.com/labstack/echo/v4"
+)
+
+// AdminPrefix is where the gateway serves its own administrative endpoints.
+const AdminPrefix = "/_gateway"
+
+// RouteInfo describes a single route registered inside a module router.
+type RouteInfo struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Name       string   `json:"name,omitempty"`
+	Handler    string   `json:"handler,omitempty"`
+	Middleware []string `json:"middleware"`
+}
+
+// ModuleInfo describes a mounted module and the routes it serves.
+type ModuleInfo struct {
+	Name     string            `json:"name"`
+	Prefix   string            `json:"prefix"`
+	Hosts    []string          `json:"hosts,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Upstream string            `json:"upstream,omitempty"`
+	Variant  string            `json:"variant,omitempty"`
+	Weight   int               `json:"weight,omitempty"`
+	Routes   []RouteInfo       `json:"routes"`
+}
+
+// Describe lists the modules as the gateway would mount them. Route paths
+// include the mount prefix, and Middleware lists the gateway pipeline entries
+// that apply to the module, the module's own entries and, for routers built
+// with routing.New, the router, group and route middleware. Routes are only
+// listed for in-process Echo routers; proxied modules report their upstream
+// instead.
+func Describe(pipeline Pipeline, modules ...Module) []ModuleInfo {
+	var routes []*route
+	for _, m := range modules {
+		if rt := newRoute(m); rt != nil {
+			routes = append(routes, rt)
+		}
+	}
+
+	variants := make(map[*route]bool)
+	for _, rt := range groupVariants(slices.Clone(routes)) {
+		if rt.canary != nil {
+			for _, v := range rt.canary.variants {
+				variants[v] = true
+			}
+		}
+	}
+
+	infos := make([]ModuleInfo, 0, len(routes))
+	for _, rt := range routes {
+		m := rt.module
+
+		var chain []string
+		for _, entry := range pipeline {
+			if entry.Pre || !rt.skip[entry.Name] {
+				chain = append(chain, entry.Name)
+			}
+		}
+		for _, entry := range m.Middleware {
+			chain = append(chain, entry.Name)
+		}
+
+		info := ModuleInfo{
+			Name:     m.Name,
+			Prefix:   rt.prefix,
+			Hosts:    m.Hosts,
+			Headers:  m.Headers,
+			Upstream: m.Upstream,
+			Routes:   []RouteInfo{},
+		}
+
+		if variants[rt] {
+			info.Variant = rt.variant
+			info.Weight = m.Weight
+		}
+
+		switch router := m.Router.(type) {
+		case *routing.Router:
+			info.Routes = describeRoutes(router.Echo, router, rt.prefix, chain)
+		case *echo.Echo:
+			info.Routes = describeRoutes(router, nil, rt.prefix, chain)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// describeRoutes lists the routes of router. The handler and the middleware
+// attached inside the module come from recorded when the router was built
+// with routing.New; Echo alone only keeps the handler of unnamed routes.
+func describeRoutes(router *echo.Echo, recorded *routing.Router, prefix string, chain []string) []RouteInfo {
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	routes := make([]RouteInfo, 0, len(router.Routes()))
+	for _, r := range router.Routes() {
+		name, handler := r.Name, ""
+		if isFuncName(name) {
+			name, handler = "", routing.ShortFuncName(r.Name)
+		}
+
+		middleware := chain
+		if recorded != nil {
+			if rec, ok := recorded.Lookup(r.Method, r.Path); ok {
+				handler = rec.Handler
+				middleware = append(slices.Clone(chain), rec.Middleware...)
+			} else {
+				middleware = append(slices.Clone(chain), recorded.Middleware()...)
+			}
+		}
+
+		routes = append(routes, RouteInfo{
+			Method:     r.Method,
+			Path:       prefix + r.Path,
+			Name:       name,
+			Handler:    handler,
+			Middleware: middleware,
+		})
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// isFuncName reports whether a route name is the handler function name echo
+// assigns to unnamed routes rather than a name given by the module. Echo keeps
+// no other reference to the handler once a route has been named.
+func isFuncName(name string) bool {
+	return strings.Contains(name, "/") || strings.HasSuffix(name, "-fm")
+}
+
+// WriteTable renders module routes as an aligned text table.
+func WriteTable(w io.Writer, infos []ModuleInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "MODULE\tMETHOD\tPATH\tNAME\tHANDLER\tMIDDLEWARE")
+	for _, info := range infos {
+		module := info.Name
+		if info.Variant != "" {
+			module = fmt.Sprintf("%s (variant %s, weight %d)", info.Name, info.Variant, info.Weight)
+		}
+
+		if info.Upstream != "" {
+			fmt.Fprintf(tw, "%s\t*\t%s/*\t\tproxy %s\t\n", module, strings.TrimSuffix(info.Prefix, "/"), info.Upstream)
+			continue
+		}
+		for _, r := range info.Routes {
+			name := r.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				module, r.Method, r.Path, name, r.Handler, strings.Join(r.Middleware, ","))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// RegisterAdminRoutes mounts the gateway's introspection endpoint under
+// AdminPrefix. GET /_gateway/routes returns JSON, or a text table with
+// ?format=table.
+func RegisterAdminRoutes(gw *echo.Echo, infos []ModuleInfo) {
+	admin := gw.Group(AdminPrefix)
+
+	admin.GET("/routes", func(c echo.Context) error {
+		if c.QueryParam("format") == "table" {
+			var b strings.Builder
+			if err := WriteTable(&b, infos); err != nil {
+				return err
+			}
+			return c.String(http.StatusOK, b.String())
+		}
+		return c.JSON(http.StatusOK, infos)
+	})
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// hostPattern matches a request host exactly or, when wildcard is set, any
+// host made of exactly one extra label in front of suffix.
+type hostPattern struct {
+	wildcard bool
+	suffix   string
+}
+
+func parseHostPattern(pattern string) hostPattern {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if rest, ok := strings.CutPrefix(pattern, "*."); ok {
+		return hostPattern{wildcard: true, suffix: "." + rest}
+	}
+	return hostPattern{suffix: pattern}
+}
+
+// match reports whether host matches the pattern and returns the label
+// matched by the wildcard, if any.
+func (p hostPattern) match(host string) (string, bool) {
+	if !p.wildcard {
+		return "", host == p.suffix
+	}
+
+	label, ok := strings.CutSuffix(host, p.suffix)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+	return label, true
+}
+
+// match reports whether r should be served by the route, returning the host
+// label captured by a wildcard host pattern.
+func (rt *route) match(r *http.Request) (string, bool) {
+	if !matchPrefix(rt.prefix, r.URL.Path) {
+		return "", false
+	}
+
+	for name, want := range rt.headers {
+		got := r.Header.Get(name)
+		if got == "" || (want != "*" && got != want) {
+			return "", false
+		}
+	}
+
+	if len(rt.hosts) == 0 {
+		return "", true
+	}
+
+	host := requestHost(r)
+	for _, p := range rt.hosts {
+		if label, ok := p.match(host); ok {
+			return label, true
+		}
+	}
+	return "", false
+}
+
+func matchPrefix(prefix, path string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// requestHost returns the lower-cased request host without a port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
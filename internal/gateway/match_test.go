@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMatchHost(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		host      string
+		wantMatch bool
+		wantLabel string
+	}{
+		{"exact", "tasks.example.local", "tasks.example.local", true, ""},
+		{"exact other host", "tasks.example.local", "todos.example.local", false, ""},
+		{"exact with port", "tasks.example.local", "tasks.example.local:8080", true, ""},
+		{"exact case folded", "Tasks.Example.Local", "TASKS.example.LOCAL", true, ""},
+		{"exact trailing dot", "tasks.example.local", "tasks.example.local.", true, ""},
+		{"exact rejects subdomain", "example.local", "tasks.example.local", false, ""},
+		{"wildcard one label", "*.sites.example.local", "acme.sites.example.local", true, "acme"},
+		{"wildcard with port", "*.sites.example.local", "acme.sites.example.local:443", true, "acme"},
+		{"wildcard case folded", "*.Sites.Example.Local", "ACME.sites.example.local", true, "acme"},
+		{"wildcard rejects two labels", "*.sites.example.local", "a.b.sites.example.local", false, ""},
+		{"wildcard rejects bare suffix", "*.sites.example.local", "sites.example.local", false, ""},
+		{"wildcard rejects other suffix", "*.sites.example.local", "acme.example.local", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newRoute(Module{Name: "m", Hosts: []string{tt.pattern}, Router: http.NotFoundHandler()})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+
+			label, ok := rt.match(req)
+			if ok != tt.wantMatch || label != tt.wantLabel {
+				t.Errorf("match(%q) = (%q, %v), want (%q, %v)", tt.host, label, ok, tt.wantLabel, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestRouteMatchHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   map[string]string
+		headers map[string]string
+		want    bool
+	}{
+		{"exact value", map[string]string{"X-Tenant": "acme"}, map[string]string{"X-Tenant": "acme"}, true},
+		{"other value", map[string]string{"X-Tenant": "acme"}, map[string]string{"X-Tenant": "other"}, false},
+		{"missing header", map[string]string{"X-Tenant": "acme"}, nil, false},
+		{"case-insensitive name", map[string]string{"x-tenant": "acme"}, map[string]string{"X-TENANT": "acme"}, true},
+		{"value is case-sensitive", map[string]string{"X-Tenant": "acme"}, map[string]string{"X-Tenant": "ACME"}, false},
+		{"wildcard present", map[string]string{"X-Beta": "*"}, map[string]string{"X-Beta": "1"}, true},
+		{"wildcard missing", map[string]string{"X-Beta": "*"}, nil, false},
+		{"all rules must match", map[string]string{"X-Tenant": "acme", "X-Beta": "*"}, map[string]string{"X-Tenant": "acme"}, false},
+		{"all rules match", map[string]string{"X-Tenant": "acme", "X-Beta": "*"}, map[string]string{"X-Tenant": "acme", "X-Beta": "yes"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newRoute(Module{Name: "m", Prefix: "/m", Headers: tt.rules, Router: http.NotFoundHandler()})
+
+			req := httptest.NewRequest(http.MethodGet, "/m/x", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			if _, ok := rt.match(req); ok != tt.want {
+				t.Errorf("match = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteMatchPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   bool
+	}{
+		{"/task", "/task", true},
+		{"/task", "/task/", true},
+		{"/task", "/task/api/tasks", true},
+		{"/task", "/tasks", false},
+		{"/task", "/", false},
+		{"/", "/anything", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPrefix(tt.prefix, tt.path); got != tt.want {
+			t.Errorf("matchPrefix(%q, %q) = %v, want %v", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/goku-m/main/internal/shared/server"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterMetricsRoute serves Prometheus metrics at /_gateway/metrics.
+func RegisterMetricsRoute(gw *echo.Echo, s *server.Server) {
+	gw.GET(AdminPrefix+"/metrics", func(c echo.Context) error {
+		var b bytes.Buffer
+		if s.DB != nil {
+			if err := s.DB.WritePrometheus(&b); err != nil {
+				return err
+			}
+		}
+		return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", b.Bytes())
+	})
+}
@@ -1,9 +1,14 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/urls"
 	"github.com/labstack/echo/v4"
 )
 
@@ -11,29 +16,209 @@ type Module struct {
 	Name   string
 	Prefix string
 	Router http.Handler
+
+	// Hosts routes the module by request host, either exactly
+	// ("tasks.example.local") or with a single leading wildcard label
+	// ("*.sites.example.local"). The label matched by the wildcard is exposed
+	// to the module as urls.Mount.HostMatch. Host modules without a Prefix own
+	// the whole host.
+	Hosts []string
+	// Headers restricts the module to requests whose headers carry the given
+	// values. A value of "*" only requires the header to be present.
+	Headers map[string]string
+
+	// Middleware runs after the gateway pipeline for this module only.
+	Middleware []Middleware
+	// SkipMiddleware names gateway pipeline entries this module opts out of.
+	SkipMiddleware []string
+
+	// Variant names the module within a canary group, defaulting to Name.
+	// Modules with identical prefix, host and header rules form a group that
+	// splits traffic by Weight; clients stay on their variant through
+	// VariantCookie and can be pinned with VariantHeader. A variant with zero
+	// weight only serves pinned requests, unless no variant has a weight.
+	Variant string
+	Weight  int
+
+	// Upstream serves the module from a separately deployed service instead
+	// of an in-process Router, e.g. "http://task:8080". Build replaces it with
+	// a reverse proxy that actively health checks the upstream.
+	Upstream    string
+	HealthCheck HealthCheck
+
+	// Optional lifecycle hooks. Start runs before the server accepts traffic,
+	// Ready blocks until the module can serve requests and Stop runs during
+	// shutdown. Modules are started in dependency order and stopped in reverse.
+	Start func(ctx context.Context) error
+	Ready func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+	// Timeout bounds each lifecycle hook; zero uses server.DefaultHookTimeout.
+	Timeout time.Duration
+}
+
+// route is a mounted module together with its compiled match rules.
+type route struct {
+	module  Module
+	prefix  string
+	hosts   []hostPattern
+	headers map[string]string
+	skip    map[string]bool
+	handler echo.HandlerFunc
+
+	variant string
+	canary  *canary
+}
+
+func (m Module) hasLifecycle() bool {
+	return m.Start != nil || m.Ready != nil || m.Stop != nil
 }
 
-func New(modules ...Module) *echo.Echo {
+// New builds the gateway router. Every request first passes through the Pre
+// entries of the pipeline, then resolves the module that will serve it, then
+// passes once through the rest of the shared pipeline (minus the entries the
+// module opted out of), then through the module's own middleware.
+// Modules with host or header rules are matched first, in the order given;
+// the remaining modules are matched by the longest path prefix. Requests from
+// the trusted proxies may set the external origin of the request with
+// X-Forwarded-Proto and X-Forwarded-Host.
+func New(pipeline Pipeline, trusted urls.TrustedProxies, modules ...Module) *echo.Echo {
 	gw := echo.New()
 
+	var routes []*route
 	for _, m := range modules {
-		mount(gw, m)
+		if r := newRoute(m); r != nil {
+			routes = append(routes, r)
+		}
 	}
+	sortRoutes(routes)
+	routes = groupVariants(routes)
+
+	for _, m := range pipeline {
+		if m.Pre {
+			gw.Pre(m.Func)
+		}
+	}
+	gw.Pre(resolve(routes, trusted))
+	for _, m := range pipeline {
+		if !m.Pre {
+			gw.Use(m.skippable())
+		}
+	}
+
+	dispatch := func(c echo.Context) error {
+		rt := routeFrom(c)
+		if rt == nil {
+			return echo.ErrNotFound
+		}
+		return rt.handler(c)
+	}
+
+	gw.Any("/", dispatch)
+	gw.Any("/*", dispatch)
 
 	return gw
 }
 
-func mount(gw *echo.Echo, module Module) {
+// resolve picks the route for the request before the pipeline runs, so that
+// pipeline entries can honour the module's opt-outs. For canary groups it
+// also picks the variant and records it for the request log.
+func resolve(routes []*route, trusted urls.TrustedProxies) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			for _, rt := range routes {
+				hostMatch, ok := rt.match(req)
+				if !ok {
+					continue
+				}
+
+				if rt.canary != nil {
+					variant, assigned := rt.canary.pick(req)
+					if assigned {
+						c.SetCookie(variantCookie(rt.prefix, variant.variant))
+					}
+					c.Set(middleware.VariantKey, variant.variant)
+					rt = variant
+				}
+
+				mnt := urls.MountFromRequest(req, rt.prefix, trusted)
+				mnt.HostMatch = hostMatch
+				c.SetRequest(req.WithContext(urls.WithMount(req.Context(), mnt)))
+				c.Set(routeKey, rt)
+				c.Set(middleware.ModuleKey, rt.module.Name)
+				break
+			}
+			return next(c)
+		}
+	}
+}
+
+func routeFrom(c echo.Context) *route {
+	rt, _ := c.Get(routeKey).(*route)
+	return rt
+}
+
+func newRoute(module Module) *route {
 	if module.Router == nil {
-		return
+		return nil
+	}
+
+	prefix := module.Prefix
+	if prefix == "" && len(module.Hosts) > 0 {
+		prefix = "/"
+	}
+	prefix = normalizePrefix(prefix, module.Name)
+
+	hosts := make([]hostPattern, 0, len(module.Hosts))
+	for _, h := range module.Hosts {
+		hosts = append(hosts, parseHostPattern(h))
+	}
+
+	skip := make(map[string]bool, len(module.SkipMiddleware))
+	for _, name := range module.SkipMiddleware {
+		skip[name] = true
 	}
 
-	prefix := normalizePrefix(module.Prefix, module.Name)
-	handler := stripPrefix(prefix, module.Router)
+	handler := echo.WrapHandler(stripPrefix(prefix, module.Router))
+	for i := len(module.Middleware) - 1; i >= 0; i-- {
+		handler = module.Middleware[i].Func(handler)
+	}
+
+	variant := module.Variant
+	if variant == "" {
+		variant = module.Name
+	}
+
+	return &route{
+		module:  module,
+		prefix:  prefix,
+		hosts:   hosts,
+		headers: module.Headers,
+		skip:    skip,
+		handler: handler,
+		variant: variant,
+	}
+}
 
-	// Match both the prefix root and any subpaths.
-	gw.Any(prefix, echo.WrapHandler(handler))
-	gw.Any(prefix+"/*", echo.WrapHandler(handler))
+// conditional reports whether the route has host or header rules, which make
+// it more specific than a route matched on its path prefix alone.
+func (rt *route) conditional() bool {
+	return len(rt.hosts) > 0 || len(rt.headers) > 0
+}
+
+// sortRoutes orders conditional routes first, keeping their declared order,
+// and then the remaining routes by descending prefix length.
+func sortRoutes(routes []*route) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.conditional() != b.conditional() {
+			return a.conditional()
+		}
+		if a.conditional() {
+			return false
+		}
+		return len(a.prefix) > len(b.prefix)
+	})
 }
 
 func normalizePrefix(prefix, name string) string {
@@ -49,16 +234,18 @@ func normalizePrefix(prefix, name string) string {
 	return prefix
 }
 
+// stripPrefix removes the mount prefix before handing the request to the module.
 func stripPrefix(prefix string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		if strings.HasPrefix(path, prefix) {
+		if prefix != "/" && strings.HasPrefix(path, prefix) {
 			path = strings.TrimPrefix(path, prefix)
 		}
 		if path == "" {
 			path = "/"
 		}
 		r.URL.Path = path
+		r.URL.RawPath = ""
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"net/http"
+	"slices"
+	"sync/atomic"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/server"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// Names of the entries in the default pipeline. Modules refer to them in
+// Module.SkipMiddleware to opt out of an entry.
+const (
+	MiddlewareRateLimit     = "rate_limit"
+	MiddlewareCORS          = "cors"
+	MiddlewareSecure        = "secure"
+	MiddlewareRequestID     = "request_id"
+	MiddlewareContext       = "context"
+	MiddlewareRequestLogger = "request_logger"
+	MiddlewareRecover       = "recover"
+)
+
+const routeKey = "gateway.route"
+
+// Middleware is a named entry of the gateway pipeline.
+type Middleware struct {
+	Name string
+	Func echo.MiddlewareFunc
+	// Pre entries run before the gateway resolves the module, so they wrap
+	// the routing and every other entry. Modules cannot opt out of them.
+	Pre bool
+}
+
+// Pipeline is the ordered middleware chain every request passes through once,
+// before it reaches the module that serves it.
+type Pipeline []Middleware
+
+// skippable wraps the middleware so it is bypassed for modules that opted out.
+func (m Middleware) skippable() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		wrapped := m.Func(next)
+		return func(c echo.Context) error {
+			if rt := routeFrom(c); rt != nil && rt.skip[m.Name] {
+				return next(c)
+			}
+			return wrapped(c)
+		}
+	}
+}
+
+// With returns a copy of the pipeline with extra entries appended.
+func (p Pipeline) With(extra ...Middleware) Pipeline {
+	out := make(Pipeline, 0, len(p)+len(extra))
+	out = append(out, p...)
+	return append(out, extra...)
+}
+
+// DefaultPipeline is the edge middleware stack shared by every module: panic
+// recovery, rate limiting, CORS, security headers, request IDs, the
+// request-scoped logger and request logging. Recovery comes first and runs
+// before routing, so a panic anywhere in the gateway is recovered too. The
+// rate limit and CORS origins follow config reloads.
+func DefaultPipeline(s *server.Server, middlewares *middleware.Middlewares) Pipeline {
+	limits := newLimiterStore(s.Config.Server)
+
+	if s.Reloader != nil {
+		s.Reloader.Subscribe("gateway.pipeline", func(old, current *config.Config) {
+			if !slices.Equal(old.Server.CORSAllowedOrigins, current.Server.CORSAllowedOrigins) {
+				middlewares.Global.SetCORSOrigins(current.Server.CORSAllowedOrigins)
+			}
+			if old.Server.RateLimit != current.Server.RateLimit ||
+				old.Server.RateLimitBurst != current.Server.RateLimitBurst {
+				limits.set(current.Server)
+			}
+		})
+	}
+
+	return Pipeline{
+		{Name: MiddlewareRecover, Func: middlewares.Global.Recover(), Pre: true},
+		{Name: MiddlewareRateLimit, Func: rateLimiter(s, middlewares, limits)},
+		{Name: MiddlewareCORS, Func: middlewares.Global.CORS()},
+		{Name: MiddlewareSecure, Func: middlewares.Global.Secure()},
+		{Name: MiddlewareRequestID, Func: middleware.RequestID()},
+		{Name: MiddlewareContext, Func: middlewares.ContextEnhancer.EnhanceContext()},
+		{Name: MiddlewareRequestLogger, Func: middlewares.Global.RequestLogger()},
+	}
+}
+
+// limiterStore lets the rate limit change at runtime. Changing it starts
+// every client with a fresh bucket.
+type limiterStore struct {
+	store atomic.Pointer[echoMiddleware.RateLimiterMemoryStore]
+}
+
+func newLimiterStore(cfg config.ServerConfig) *limiterStore {
+	l := &limiterStore{}
+	l.set(cfg)
+	return l
+}
+
+func (l *limiterStore) set(cfg config.ServerConfig) {
+	l.store.Store(echoMiddleware.NewRateLimiterMemoryStoreWithConfig(echoMiddleware.RateLimiterMemoryStoreConfig{
+		Rate:  rate.Limit(cfg.RateLimit),
+		Burst: cfg.RateLimitBurst,
+	}))
+}
+
+func (l *limiterStore) Allow(identifier string) (bool, error) {
+	return l.store.Load().Allow(identifier)
+}
+
+func rateLimiter(s *server.Server, middlewares *middleware.Middlewares, limits *limiterStore) echo.MiddlewareFunc {
+	return echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
+		Store: limits,
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			// Record rate limit hit metrics
+			if rateLimitMiddleware := middlewares.RateLimit; rateLimitMiddleware != nil {
+				rateLimitMiddleware.RecordRateLimitHit(c.Request().URL.Path)
+			}
+
+			s.Logger.Warn().
+				Str("request_id", middleware.GetRequestID(c)).
+				Str("identifier", identifier).
+				Str("path", c.Request().URL.Path).
+				Str("method", c.Request().Method).
+				Str("ip", c.RealIP()).
+				Msg("rate limit exceeded")
+
+			return echo.NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded")
+		},
+	})
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/server"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+)
+
+func panics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		panic("boom")
+	}
+}
+
+func TestRecoverWrapsWholeGateway(t *testing.T) {
+	recoverEntry := Middleware{Name: MiddlewareRecover, Func: echoMiddleware.Recover(), Pre: true}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name     string
+		pipeline Pipeline
+		module   Module
+	}{
+		{
+			name:     "pipeline entry",
+			pipeline: Pipeline{recoverEntry, {Name: "boom", Func: panics}},
+			module:   Module{Name: "m", Router: handler},
+		},
+		{
+			name:     "pipeline entry listed before recover",
+			pipeline: Pipeline{{Name: "boom", Func: panics}, recoverEntry},
+			module:   Module{Name: "m", Router: handler},
+		},
+		{
+			name:     "module middleware",
+			pipeline: Pipeline{recoverEntry},
+			module:   Module{Name: "m", Router: handler, Middleware: []Middleware{{Name: "boom", Func: panics}}},
+		},
+		{
+			name:     "module handler",
+			pipeline: Pipeline{recoverEntry},
+			module: Module{Name: "m", Router: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})},
+		},
+		{
+			name:     "skipped by the module",
+			pipeline: Pipeline{recoverEntry, {Name: "boom", Func: panics}},
+			module:   Module{Name: "m", Router: http.NotFoundHandler(), SkipMiddleware: []string{MiddlewareRecover}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := New(tt.pipeline, nil, tt.module)
+
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/m/x", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestRequestLogCarriesModuleUser(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+	s := &server.Server{Logger: &logger, Config: &config.Config{}}
+	middlewares := middleware.NewMiddlewares(s)
+
+	router := echo.New()
+	router.GET("/x", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, middlewares.Auth.RequireAuthIP)
+
+	gw := New(Pipeline{
+		{Name: MiddlewareRequestID, Func: middleware.RequestID()},
+		{Name: MiddlewareContext, Func: middlewares.ContextEnhancer.EnhanceContext()},
+		{Name: MiddlewareRequestLogger, Func: middlewares.Global.RequestLogger()},
+	}, nil, Module{Name: "m", Router: router})
+
+	req := httptest.NewRequest(http.MethodGet, "/m/x", nil)
+	req.RemoteAddr = "192.0.2.7:1234"
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(logs.String(), `"user_id":"192.0.2.7"`) {
+		t.Errorf("request log has no user_id: %s", logs.String())
+	}
+}
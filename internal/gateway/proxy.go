@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/errs"
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/goku-m/main/internal/shared/urls"
+	"github.com/goku-m/main/internal/shared/worker"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultHealthCheckPath      = "/status"
+	DefaultHealthCheckInterval  = 10 * time.Second
+	DefaultHealthCheckTimeout   = 2 * time.Second
+	DefaultHealthCheckThreshold = 2
+)
+
+// ForwardedPrefixHeader tells the upstream which prefix the gateway stripped.
+const ForwardedPrefixHeader = "X-Forwarded-Prefix"
+
+// HealthCheck configures active health checks against a module upstream.
+// Zero values fall back to the Default* constants.
+type HealthCheck struct {
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+	// Threshold is the number of consecutive failures before the upstream is
+	// marked unhealthy. A single successful check marks it healthy again.
+	Threshold int
+}
+
+func (hc HealthCheck) withDefaults() HealthCheck {
+	if hc.Path == "" {
+		hc.Path = DefaultHealthCheckPath
+	}
+	if hc.Interval <= 0 {
+		hc.Interval = DefaultHealthCheckInterval
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = DefaultHealthCheckTimeout
+	}
+	if hc.Threshold <= 0 {
+		hc.Threshold = DefaultHealthCheckThreshold
+	}
+	return hc
+}
+
+// upstreamProxy forwards module traffic to a separately deployed service and
+// tracks its health so requests fail fast while the upstream is down.
+type upstreamProxy struct {
+	name   string
+	target *url.URL
+	check  HealthCheck
+	proxy  *httputil.ReverseProxy
+	client *http.Client
+	logger *zerolog.Logger
+
+	healthy  atomic.Bool
+	failures int
+	checker  *worker.Worker
+}
+
+func newUpstreamProxy(name, upstream string, check HealthCheck, logger *zerolog.Logger) (*upstreamProxy, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q for module %q: %w", upstream, name, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream %q for module %q: scheme and host are required", upstream, name)
+	}
+
+	p := &upstreamProxy{
+		name:   name,
+		target: target,
+		check:  check.withDefaults(),
+		client: &http.Client{},
+		logger: logger,
+	}
+	p.healthy.Store(true)
+	p.checker = worker.Every("health check of "+name, p.check.Interval, p.probe)
+
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		ModifyResponse: p.modifyResponse,
+		FlushInterval:  -1, // stream response bodies as they arrive
+		ErrorHandler:   p.handleError,
+	}
+
+	return p, nil
+}
+
+// rewrite points the outgoing request at the upstream. The gateway has
+// already stripped the mount prefix, so the remaining path is joined onto the
+// upstream path exactly as an in-process router would see it.
+func (p *upstreamProxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.SetURL(p.target)
+	pr.SetXForwarded()
+
+	if prefix := urls.MountFrom(pr.In.Context()).Prefix; prefix != "" {
+		pr.Out.Header.Set(ForwardedPrefixHeader, prefix)
+	}
+	if id := pr.In.Header.Get(middleware.RequestIDHeader); id != "" {
+		pr.Out.Header.Set(middleware.RequestIDHeader, id)
+	}
+}
+
+// modifyResponse returns the request ID to the client. Upstreams usually
+// echo it themselves, and the proxy adds their headers to the ones already on
+// the response, so it is only set when the upstream left it out.
+func (p *upstreamProxy) modifyResponse(res *http.Response) error {
+	if res.Header.Get(middleware.RequestIDHeader) == "" {
+		res.Header.Set(middleware.RequestIDHeader, res.Request.Header.Get(middleware.RequestIDHeader))
+	}
+	return nil
+}
+
+func (p *upstreamProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Header.Get(middleware.RequestIDHeader)
+	if requestID == "" {
+		requestID = uuid.New().String()
+		r.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+	// Set by the gateway pipeline, it would be sent twice next to the
+	// upstream's; modifyResponse and the error paths set it instead.
+	w.Header().Del(middleware.RequestIDHeader)
+
+	if !p.healthy.Load() {
+		w.Header().Set(middleware.RequestIDHeader, requestID)
+		writeGatewayError(w, http.StatusServiceUnavailable, "Upstream is unavailable")
+		return
+	}
+
+	p.proxy.ServeHTTP(w, r)
+}
+
+func (p *upstreamProxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	p.logger.Error().
+		Err(err).
+		Str("module", p.name).
+		Str("upstream", p.target.String()).
+		Str("request_id", r.Header.Get(middleware.RequestIDHeader)).
+		Str("path", r.URL.Path).
+		Msg("upstream request failed")
+
+	w.Header().Set(middleware.RequestIDHeader, r.Header.Get(middleware.RequestIDHeader))
+	writeGatewayError(w, http.StatusBadGateway, "Upstream request failed")
+}
+
+// Start begins active health checking. The first check runs immediately.
+func (p *upstreamProxy) Start(ctx context.Context) error {
+	err := p.checker.Start(ctx)
+	if errors.Is(err, worker.ErrRunning) {
+		return nil
+	}
+	return err
+}
+
+// Stop ends health checking and waits for the checker to exit.
+func (p *upstreamProxy) Stop(ctx context.Context) error {
+	return p.checker.Stop(ctx)
+}
+
+func (p *upstreamProxy) probe(ctx context.Context) {
+	err := p.ping(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		p.failures = 0
+		if !p.healthy.Swap(true) {
+			p.logger.Info().
+				Str("module", p.name).
+				Str("upstream", p.target.String()).
+				Msg("upstream is healthy again")
+		}
+		return
+	}
+
+	p.failures++
+	p.logger.Warn().
+		Err(err).
+		Str("module", p.name).
+		Str("upstream", p.target.String()).
+		Int("consecutive_failures", p.failures).
+		Msg("upstream health check failed")
+
+	if p.failures >= p.check.Threshold && p.healthy.Swap(false) {
+		p.logger.Error().
+			Str("module", p.name).
+			Str("upstream", p.target.String()).
+			Msg("upstream marked unhealthy")
+	}
+}
+
+func (p *upstreamProxy) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.check.Timeout)
+	defer cancel()
+
+	target := p.target.JoinPath(p.check.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// withUpstream turns a module that declares an Upstream into one served by a
+// reverse proxy, chaining the health checker into the module lifecycle.
+func withUpstream(m Module, logger *zerolog.Logger) (Module, error) {
+	p, err := newUpstreamProxy(m.Name, m.Upstream, m.HealthCheck, logger)
+	if err != nil {
+		return Module{}, err
+	}
+
+	m.Router = p
+	m.Start = chainHooks(p.Start, m.Start)
+	m.Stop = chainHooks(m.Stop, p.Stop)
+
+	return m, nil
+}
+
+// chainHooks runs the given hooks in order, skipping nil ones.
+func chainHooks(hooks ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, h := range hooks {
+			if h == nil {
+				continue
+			}
+			if err := h(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func writeGatewayError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errs.HTTPError{
+		Code:    errs.MakeUpperCaseWithUnderscores(http.StatusText(status)),
+		Message: message,
+		Status:  status,
+	})
+}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/middleware"
+	"github.com/rs/zerolog"
+)
+
+// newProxyGateway mounts upstream under /svc behind a gateway with the given
+// pipeline and serves it over HTTP.
+func newProxyGateway(t *testing.T, upstream string, pipeline ...Middleware) *httptest.Server {
+	t.Helper()
+
+	logger := zerolog.Nop()
+	m, err := withUpstream(Module{Name: "svc", Prefix: "/svc", Upstream: upstream}, &logger)
+	if err != nil {
+		t.Fatalf("withUpstream: %v", err)
+	}
+
+	gw := httptest.NewServer(New(pipeline, nil, m))
+	t.Cleanup(gw.Close)
+	return gw
+}
+
+func TestProxyStripsPrefixAndForwardsHeaders(t *testing.T) {
+	seen := make(chan *http.Request, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Clone(context.Background())
+	}))
+	defer upstream.Close()
+
+	gw := newProxyGateway(t, upstream.URL)
+
+	req, _ := http.NewRequest(http.MethodGet, gw.URL+"/svc/items/1?q=x", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Values(middleware.RequestIDHeader); len(got) != 1 || got[0] != "req-123" {
+		t.Errorf("response %s = %q, want req-123", middleware.RequestIDHeader, got)
+	}
+
+	r := <-seen
+	if r.URL.Path != "/items/1" || r.URL.RawQuery != "q=x" {
+		t.Errorf("upstream got %s?%s, want /items/1?q=x", r.URL.Path, r.URL.RawQuery)
+	}
+
+	for header, want := range map[string]string{
+		ForwardedPrefixHeader:      "/svc",
+		middleware.RequestIDHeader: "req-123",
+		"X-Forwarded-Host":         strings.TrimPrefix(gw.URL, "http://"),
+		"X-Forwarded-Proto":        "http",
+	} {
+		if got := r.Header.Get(header); got != want {
+			t.Errorf("upstream %s = %q, want %q", header, got, want)
+		}
+	}
+	if r.Header.Get("X-Forwarded-For") == "" {
+		t.Error("upstream X-Forwarded-For is empty")
+	}
+}
+
+func TestProxyGeneratesRequestID(t *testing.T) {
+	seen := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Header.Get(middleware.RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	gw := newProxyGateway(t, upstream.URL)
+
+	resp, err := http.Get(gw.URL + "/svc/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	sent := <-seen
+	if sent == "" {
+		t.Fatal("upstream got no request ID")
+	}
+	if got := resp.Header.Get(middleware.RequestIDHeader); got != sent {
+		t.Errorf("response request ID = %q, upstream got %q", got, sent)
+	}
+}
+
+func TestProxySendsRequestIDOnce(t *testing.T) {
+	// Like the modules of this repo, the upstream echoes the request ID
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, r.Header.Get(middleware.RequestIDHeader))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name     string
+		pipeline []Middleware
+	}{
+		{"without pipeline", nil},
+		{"behind the request ID middleware", []Middleware{{Name: MiddlewareRequestID, Func: middleware.RequestID()}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := newProxyGateway(t, upstream.URL, tt.pipeline...)
+
+			req, _ := http.NewRequest(http.MethodGet, gw.URL+"/svc/", nil)
+			req.Header.Set(middleware.RequestIDHeader, "req-123")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if got := resp.Header.Values(middleware.RequestIDHeader); len(got) != 1 || got[0] != "req-123" {
+				t.Errorf("response %s = %q, want one req-123", middleware.RequestIDHeader, got)
+			}
+		})
+	}
+}
+
+func TestProxyStreamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "first\n")
+		w.(http.Flusher).Flush()
+
+		// The rest only arrives once the client has seen the first line.
+		<-release
+		io.WriteString(w, "second\n")
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	gw := newProxyGateway(t, upstream.URL)
+
+	resp, err := http.Get(gw.URL + "/svc/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "first\n" {
+			t.Errorf("first line = %q, want %q", line, "first\n")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first chunk was not flushed to the client")
+	}
+}
+
+func TestProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	url := upstream.URL
+	upstream.Close()
+
+	gw := newProxyGateway(t, url)
+
+	resp, err := http.Get(gw.URL + "/svc/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if got := resp.Header.Values(middleware.RequestIDHeader); len(got) != 1 || got[0] == "" {
+		t.Errorf("response %s = %q, want one generated ID", middleware.RequestIDHeader, got)
+	}
+}
+
+func TestProxyHealthCheck(t *testing.T) {
+	failing := true
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == DefaultHealthCheckPath && failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+
+	logger := zerolog.Nop()
+	p, err := newUpstreamProxy("svc", upstream.URL, HealthCheck{Threshold: 2}, &logger)
+	if err != nil {
+		t.Fatalf("newUpstreamProxy: %v", err)
+	}
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	p.probe(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("after one failed check status = %d, want 200", code)
+	}
+
+	p.probe(context.Background())
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Fatalf("after threshold status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	failing = false
+	p.probe(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("after recovery status = %d, want 200", code)
+	}
+}
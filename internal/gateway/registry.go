@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/server"
+)
+
+// Factory describes how to build a module. Apps register a factory from an
+// init function so the gateway can construct whichever modules are enabled
+// without the entry point importing each app by hand.
+type Factory struct {
+	Name      string
+	Prefix    string
+	DependsOn []string
+	New       func(s *server.Server) (Module, error)
+
+	// Schema is the Postgres schema the module owns. When set, New gets a
+	// server whose DB pins search_path to it and rejects statements naming
+	// the tables of other module schemas, except those in UsesSchemas.
+	Schema      string
+	UsesSchemas []string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register adds a module factory to the registry. It panics when the factory
+// is incomplete or a factory with the same name was already registered, since
+// both are programming errors that should fail at startup.
+func Register(f Factory) {
+	if f.Name == "" {
+		panic("gateway: module factory registered without a name")
+	}
+	if f.New == nil {
+		panic(fmt.Sprintf("gateway: module factory %q has no constructor", f.Name))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[f.Name]; exists {
+		panic(fmt.Sprintf("gateway: module factory %q registered twice", f.Name))
+	}
+	registry[f.Name] = f
+}
+
+// Registered returns the names of all registered module factories, sorted.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build constructs the enabled modules in dependency order. An empty module
+// list enables every registered module. Dependencies of an enabled module must
+// be enabled as well. Modules with a configured upstream are proxied to that
+// service instead of being constructed in process. Lifecycle hooks of the
+// built modules are registered with the server in the same order.
+func Build(s *server.Server, cfg config.GatewayConfig) ([]Module, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	enabled := cfg.Modules
+
+	if len(enabled) == 0 {
+		for name := range registry {
+			enabled = append(enabled, name)
+		}
+	}
+
+	selected := make(map[string]Factory, len(enabled))
+	for _, name := range enabled {
+		f, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("module %q is enabled but not registered", name)
+		}
+		selected[name] = f
+	}
+
+	order, err := resolveOrder(selected)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]Module, 0, len(order))
+	for _, f := range order {
+		m, err := newModule(s, f, cfg.Upstreams[f.Name])
+		if err != nil {
+			return nil, err
+		}
+		if hosts := cfg.Hosts[f.Name]; len(hosts) > 0 {
+			m.Hosts = hosts
+			m.Prefix = "/"
+		}
+		if weight, ok := cfg.Weights[f.Name]; ok {
+			m.Weight = weight
+		}
+		if m.hasLifecycle() {
+			s.AddHook(server.Hook{
+				Name:    m.Name,
+				Timeout: m.Timeout,
+				OnStart: m.Start,
+				OnReady: m.Ready,
+				OnStop:  m.Stop,
+			})
+		}
+		modules = append(modules, m)
+	}
+
+	return modules, nil
+}
+
+func newModule(s *server.Server, f Factory, upstream string) (Module, error) {
+	var m Module
+	if upstream != "" {
+		m = Module{Upstream: upstream}
+	} else {
+		ms := s
+		// Without a database, as for the routes command, modules get the
+		// server as is.
+		if f.Schema != "" && s.DB != nil {
+			db, err := s.DB.ForSchema(f.Schema, f.UsesSchemas...)
+			if err != nil {
+				return Module{}, fmt.Errorf("could not scope database for module %q: %w", f.Name, err)
+			}
+			ms = s.WithDB(db)
+		}
+
+		var err error
+		if m, err = f.New(ms); err != nil {
+			return Module{}, fmt.Errorf("could not initialize module %q: %w", f.Name, err)
+		}
+	}
+
+	if m.Name == "" {
+		m.Name = f.Name
+	}
+	if m.Prefix == "" {
+		m.Prefix = f.Prefix
+	}
+
+	if m.Router == nil && m.Upstream != "" {
+		var err error
+		if m, err = withUpstream(m, s.Logger); err != nil {
+			return Module{}, err
+		}
+		s.Logger.Info().
+			Str("module", m.Name).
+			Str("upstream", m.Upstream).
+			Msg("proxying module to upstream")
+	}
+
+	return m, nil
+}
+
+// resolveOrder sorts factories so every module comes after its dependencies.
+// Modules without a dependency relationship keep a stable, alphabetical order.
+func resolveOrder(selected map[string]Factory) ([]Factory, error) {
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(selected))
+	order := make([]Factory, 0, len(selected))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("module dependency cycle: %v", append(path, name))
+		}
+
+		f := selected[name]
+		state[name] = visiting
+		for _, dep := range f.DependsOn {
+			if _, ok := selected[dep]; !ok {
+				return fmt.Errorf("module %q depends on %q, which is not enabled", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, f)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
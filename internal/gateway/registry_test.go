@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "no dependencies sorts by name",
+			deps: map[string][]string{"todo": nil, "task": nil, "billing": nil},
+			want: []string{"billing", "task", "todo"},
+		},
+		{
+			name: "dependency comes first",
+			deps: map[string][]string{"a": {"b"}, "b": nil},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "chain",
+			deps: map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "shared dependency appears once",
+			deps: map[string][]string{"app": {"auth", "db"}, "auth": {"db"}, "db": nil, "zeta": nil},
+			want: []string{"db", "auth", "app", "zeta"},
+		},
+		{
+			name:    "missing dependency",
+			deps:    map[string][]string{"a": {"b"}},
+			wantErr: `module "a" depends on "b", which is not enabled`,
+		},
+		{
+			name:    "cycle",
+			deps:    map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			wantErr: "module dependency cycle: [a b c a]",
+		},
+		{
+			name:    "self dependency",
+			deps:    map[string][]string{"a": {"a"}},
+			wantErr: "module dependency cycle: [a a]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := make(map[string]Factory, len(tt.deps))
+			for name, deps := range tt.deps {
+				selected[name] = Factory{Name: name, DependsOn: deps}
+			}
+
+			order, err := resolveOrder(selected)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveOrder: %v", err)
+			}
+
+			names := make([]string, len(order))
+			for i, f := range order {
+				names[i] = f.Name
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("order = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
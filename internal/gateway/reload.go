@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/goku-m/main/internal/shared/errs"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterReloadRoute mounts POST /_gateway/config/reload, which re-reads the
+// config through reload and reports the applied changes and the changes that
+// need a restart. An invalid config is rejected with its field errors.
+func RegisterReloadRoute(gw *echo.Echo, reload func() (*config.ReloadResult, error)) {
+	gw.POST(AdminPrefix+"/config/reload", func(c echo.Context) error {
+		result, err := reload()
+
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			fields := make([]errs.FieldError, 0, len(verr.Fields))
+			for _, f := range verr.Fields {
+				fields = append(fields, errs.FieldError{Field: f.Env, Error: f.Message})
+			}
+			return errs.NewBadRequestError("invalid config, nothing was reloaded", true, nil, fields, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, result)
+	})
+}
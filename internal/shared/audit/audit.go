@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/logger"
+)
+
+// Operation is the kind of change an audit entry records.
+type Operation string
+
+const (
+	OperationCreate  Operation = "create"
+	OperationUpdate  Operation = "update"
+	OperationDelete  Operation = "delete"
+	OperationRestore Operation = "restore"
+	OperationPurge   Operation = "purge"
+)
+
+// FieldChange is the value of a field before and after a change. Before is
+// nil for created rows and After is nil for purged ones.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Entry is a row of shared.audit_log.
+type Entry struct {
+	ID        int64                  `json:"id" db:"id"`
+	CreatedAt time.Time              `json:"createdAt" db:"created_at"`
+	Module    string                 `json:"module" db:"module"`
+	Entity    string                 `json:"entity" db:"entity"`
+	EntityID  string                 `json:"entityId" db:"entity_id"`
+	Operation Operation              `json:"operation" db:"operation"`
+	Actor     *string                `json:"actor" db:"actor"`
+	RequestID *string                `json:"requestId" db:"request_id"`
+	Diff      map[string]FieldChange `json:"diff" db:"diff"`
+}
+
+// ignoredFields change with every write and would only add noise.
+var ignoredFields = map[string]bool{
+	"updatedAt": true,
+	"version":   true,
+}
+
+type actorKey struct{}
+
+// WithActor stores who is making changes with ctx, typically the user ID of
+// the request.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Record adds an entry for a change of entity to the audit log of the module
+// owning db. before and after are the row before and after the change, nil
+// when it did not exist, and are compared by their JSON fields. Call it with
+// the ctx of the transaction making the change, so the entry commits with it.
+// An update that changed nothing is not recorded.
+func Record(ctx context.Context, db *database.Database, op Operation, entity, entityID string, before, after any) error {
+	diff, err := Diff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s %s: %w", entity, entityID, err)
+	}
+	if op == OperationUpdate && len(diff) == 0 {
+		return nil
+	}
+
+	_, err = db.Writer(ctx).Exec(ctx, `
+		INSERT INTO
+			audit_log (module, entity, entity_id, operation, actor, request_id, diff)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+	`, db.Schema(), entity, entityID, op,
+		nullIfEmpty(ActorFromContext(ctx)), nullIfEmpty(logger.RequestIDFromContext(ctx)), diff)
+	if err != nil {
+		return fmt.Errorf("failed to record %s of %s %s: %w", op, entity, entityID, err)
+	}
+
+	return nil
+}
+
+// Diff returns the fields whose JSON values differ between before and after.
+func Diff(before, after any) (map[string]FieldChange, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]FieldChange)
+	for name, value := range a {
+		old, ok := b[name]
+		if ignoredFields[name] || (ok && reflect.DeepEqual(old, value)) {
+			continue
+		}
+		diff[name] = FieldChange{Before: old, After: value}
+	}
+	for name, old := range b {
+		if _, ok := a[name]; ok || ignoredFields[name] {
+			continue
+		}
+		diff[name] = FieldChange{Before: old}
+	}
+
+	return diff, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
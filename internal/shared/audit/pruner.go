@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/worker"
+	"github.com/rs/zerolog"
+)
+
+// Pruner settings.
+const (
+	PruneInterval  = time.Hour
+	PruneBatchSize = 1000
+)
+
+// Pruner deletes the audit entries of one module older than its retention,
+// in batches so it never holds long locks on the log.
+type Pruner struct {
+	db        *database.Database
+	retention time.Duration
+	logger    *zerolog.Logger
+	worker    *worker.Worker
+}
+
+// NewPruner returns the pruner of the module owning db. A retention of zero
+// or less keeps entries forever.
+func NewPruner(db *database.Database, retention time.Duration, logger *zerolog.Logger) *Pruner {
+	p := &Pruner{
+		db:        db,
+		retention: retention,
+		logger:    logger,
+	}
+	p.worker = worker.Every("audit pruner", PruneInterval, p.prune)
+
+	return p
+}
+
+// Start launches the prune loop. The context only bounds startup; the loop
+// runs until Stop is called.
+func (p *Pruner) Start(ctx context.Context) error {
+	if p.retention <= 0 {
+		p.logger.Info().Msg("audit retention disabled, keeping entries forever")
+		return nil
+	}
+
+	return p.worker.Start(ctx)
+}
+
+// Stop cancels the prune loop and waits for it to exit or for ctx to expire.
+func (p *Pruner) Stop(ctx context.Context) error {
+	return p.worker.Stop(ctx)
+}
+
+func (p *Pruner) prune(ctx context.Context) {
+	module := p.db.Schema()
+	cutoff := time.Now().Add(-p.retention)
+
+	var deleted int64
+	for {
+		result, err := p.db.Writer(ctx).Exec(ctx, `
+			DELETE FROM audit_log
+			WHERE
+				id IN (
+					SELECT
+						id
+					FROM
+						audit_log
+					WHERE
+						module = $1
+						AND created_at < $2
+					LIMIT
+						$3
+				)
+		`, module, cutoff, PruneBatchSize)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				p.logger.Error().Err(err).Str("module", module).Msg("failed to prune audit log")
+			}
+			break
+		}
+
+		deleted += result.RowsAffected()
+		if result.RowsAffected() < PruneBatchSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		p.logger.Info().
+			Str("module", module).
+			Int64("deleted", deleted).
+			Time("before", cutoff).
+			Msg("pruned audit log")
+	}
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// Filter selects audit entries of a module. Zero fields match everything.
+type Filter struct {
+	Entity    string
+	EntityID  string
+	Actor     string
+	Operation Operation
+	From      *time.Time
+	To        *time.Time
+	Page      int
+	Limit     int
+}
+
+// Query returns a page of the audit entries of the module owning db matching
+// filter, newest first, and the total number of matches.
+func Query(ctx context.Context, db *database.Database, filter Filter) ([]Entry, int, error) {
+	conditions := []string{"module = @module"}
+	args := pgx.NamedArgs{"module": db.Schema()}
+
+	if filter.Entity != "" {
+		conditions = append(conditions, "entity = @entity")
+		args["entity"] = filter.Entity
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = @entity_id")
+		args["entity_id"] = filter.EntityID
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = @actor")
+		args["actor"] = filter.Actor
+	}
+	if filter.Operation != "" {
+		conditions = append(conditions, "operation = @operation")
+		args["operation"] = filter.Operation
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= @from")
+		args["from"] = *filter.From
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < @to")
+		args["to"] = *filter.To
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := db.Reader(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM audit_log"+where, args).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	page, limit := max(filter.Page, 1), filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	args["limit"] = limit
+	args["offset"] = (page - 1) * limit
+
+	rows, err := db.Reader(ctx).Query(ctx, `
+		SELECT
+			id,
+			created_at,
+			module,
+			entity,
+			entity_id,
+			operation,
+			actor,
+			request_id,
+			diff
+		FROM
+			audit_log
+	`+where+`
+		ORDER BY
+			id DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[Entry])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect audit entries: %w", err)
+	}
+
+	return entries, total, nil
+}
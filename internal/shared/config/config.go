@@ -1,14 +1,12 @@
 package config
 
 import (
-	"os"
-	"strings"
+	"fmt"
+	"time"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
 	_ "github.com/joho/godotenv/autoload"
-	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/v2"
-	"github.com/rs/zerolog"
 )
 
 type Config struct {
@@ -18,7 +16,23 @@ type Config struct {
 	Auth          AuthConfig           `koanf:"auth" validate:"required"`
 	Redis         RedisConfig          `koanf:"redis" validate:"required"`
 	Integration   IntegrationConfig    `koanf:"integration" `
+	Gateway       GatewayConfig        `koanf:"gateway"`
+	Trash         TrashConfig          `koanf:"trash"`
+	Audit         AuditConfig          `koanf:"audit"`
 	Observability *ObservabilityConfig `koanf:"observability"`
+
+	sources Sources
+}
+
+// Source reports which layer set a config key, such as "server.port". Keys
+// left at their zero value or filled in by defaults have no source.
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// Sources returns the source of every key set by a config layer.
+func (c *Config) Sources() Sources {
+	return c.sources
 }
 
 type Primary struct {
@@ -31,56 +45,165 @@ type ServerConfig struct {
 	WriteTimeout       int      `koanf:"write_timeout" validate:"required"`
 	IdleTimeout        int      `koanf:"idle_timeout" validate:"required"`
 	CORSAllowedOrigins []string `koanf:"cors_allowed_origins" `
+	// RateLimit is the sustained number of requests per second allowed per
+	// client IP, and RateLimitBurst how many may arrive at once.
+	RateLimit      float64 `koanf:"rate_limit" validate:"min=0"`
+	RateLimitBurst int     `koanf:"rate_limit_burst" validate:"min=0"`
 }
 
+// DefaultRateLimit applies when no rate limit is configured.
+const DefaultRateLimit = 20
+
 type DatabaseConfig struct {
 	Host            string `koanf:"host" validate:"required"`
 	Port            int    `koanf:"port" validate:"required"`
 	User            string `koanf:"user" validate:"required"`
-	Password        string `koanf:"password"`
+	Password        Secret `koanf:"password"`
 	Name            string `koanf:"name" validate:"required"`
 	SSLMode         string `koanf:"ssl_mode" validate:"required"`
 	MaxOpenConns    int    `koanf:"max_open_conns" validate:"required"`
-	MaxIdleConns    int    `koanf:"max_idle_conns" validate:"required"`
 	ConnMaxLifetime int    `koanf:"conn_max_lifetime" validate:"required"`
 	ConnMaxIdleTime int    `koanf:"conn_max_idle_time" validate:"required"`
+	// MinIdleConns is how many idle connections the pool keeps open ahead of
+	// demand.
+	MinIdleConns int `koanf:"min_idle_conns" validate:"min=0"`
+	// Deprecated: MaxIdleConns has no pgxpool counterpart and is ignored.
+	// pgxpool does not cap idle connections; it closes them after
+	// ConnMaxIdleTime. Use MinIdleConns to keep connections warm.
+	MaxIdleConns int `koanf:"max_idle_conns"`
+	// ConnectAttempts bounds how often the first connection is tried while
+	// Postgres comes up. ConnectBackoff is the wait after the first failure,
+	// doubled after each further failure up to ConnectMaxBackoff.
+	ConnectAttempts   int           `koanf:"connect_attempts" validate:"min=0"`
+	ConnectBackoff    time.Duration `koanf:"connect_backoff" validate:"min=0"`
+	ConnectMaxBackoff time.Duration `koanf:"connect_max_backoff" validate:"min=0"`
+	// Replica is an optional read replica. It is enabled by setting its host
+	// or name; the fields left empty are taken from the primary.
+	Replica ReplicaConfig `koanf:"replica"`
+}
+
+type ReplicaConfig struct {
+	Host     string `koanf:"host"`
+	Port     int    `koanf:"port" validate:"min=0"`
+	User     string `koanf:"user"`
+	Password Secret `koanf:"password"`
+	Name     string `koanf:"name"`
+}
+
+// ReplicaDatabase returns the settings of the read replica, filled in from
+// the primary, and whether a replica is configured at all.
+func (c DatabaseConfig) ReplicaDatabase() (DatabaseConfig, bool) {
+	r := c.Replica
+	if r.Host == "" && r.Name == "" {
+		return DatabaseConfig{}, false
+	}
+
+	replica := c
+	replica.Replica = ReplicaConfig{}
+	if r.Host != "" {
+		replica.Host = r.Host
+	}
+	if r.Port != 0 {
+		replica.Port = r.Port
+	}
+	if r.User != "" {
+		replica.User = r.User
+	}
+	if r.Password != "" {
+		replica.Password = r.Password
+	}
+	if r.Name != "" {
+		replica.Name = r.Name
+	}
+	return replica, true
 }
+
 type RedisConfig struct {
 	Address string `koanf:"address"  ` //validate:"required"
 }
 
 type IntegrationConfig struct {
-	ResendAPIKey string `koanf:"resend_api_key" ` //validate:"required"
+	ResendAPIKey Secret `koanf:"resend_api_key"` //validate:"required"
 }
 
-type AuthConfig struct {
-	SecretKey string `koanf:"secret_key" ` //validate:"required"
+// GatewayConfig selects which registered app modules the gateway mounts.
+// Leaving Modules empty mounts every registered module. Upstreams maps a
+// module name to the base URL of a separately deployed instance, which the
+// gateway then proxies to instead of running the module in process. Hosts
+// maps a module name to host patterns that serve the module at the host root
+// instead of under its path prefix. Weights overrides the traffic weight of
+// modules that share a prefix as canary variants. AdminEnabled exposes the
+// gateway's introspection endpoints under /_gateway, and MetricsEnabled
+// serves Prometheus metrics at /_gateway/metrics. TrustedProxies lists the
+// addresses or CIDR ranges of the proxies in front of the gateway, whose
+// X-Forwarded-Host and X-Forwarded-Proto are used to build absolute URLs.
+type GatewayConfig struct {
+	Modules        []string            `koanf:"modules"`
+	Upstreams      map[string]string   `koanf:"upstreams"`
+	Hosts          map[string][]string `koanf:"hosts"`
+	Weights        map[string]int      `koanf:"weights"`
+	AdminEnabled   bool                `koanf:"admin_enabled"`
+	MetricsEnabled bool                `koanf:"metrics_enabled"`
+	TrustedProxies []string            `koanf:"trusted_proxies"`
 }
 
-func LoadConfig() (*Config, error) {
-	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+// TrashConfig controls deleted rows. They stay in the trash, where they can
+// be restored, for Retention and are then purged for good.
+type TrashConfig struct {
+	Retention time.Duration `koanf:"retention" validate:"min=0"`
+}
+
+// DefaultTrashRetention applies when no trash retention is configured.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// AuditConfig controls the audit log of row changes. Entries older than
+// Retention are pruned; a negative Retention keeps them forever.
+type AuditConfig struct {
+	Retention time.Duration `koanf:"retention"`
+}
 
-	k := koanf.New(".")
+// DefaultAuditRetention applies when no audit retention is configured.
+const DefaultAuditRetention = 365 * 24 * time.Hour
 
-	err := k.Load(env.Provider("MAIN_", ".", func(s string) string {
-		return strings.ToLower(strings.TrimPrefix(s, "MAIN_"))
-	}), nil)
+type AuthConfig struct {
+	SecretKey Secret `koanf:"secret_key"` //validate:"required"
+}
+
+// LoadConfig loads the config layers, applies defaults and validates the
+// result. Validation failures are reported together as a *ValidationError.
+func LoadConfig() (*Config, error) {
+	k, sources, err := loadLayers()
 	if err != nil {
-		logger.Fatal().Err(err).Msg("could not load initial env variables")
+		return nil, fmt.Errorf("could not load config: %w", err)
 	}
 
-	mainConfig := &Config{}
-
-	err = k.Unmarshal("", mainConfig)
+	mainConfig := &Config{sources: sources}
+
+	err = k.UnmarshalWithConf("", mainConfig, koanf.UnmarshalConf{
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				// Lists such as CORS origins and gateway modules are
+				// comma-separated in environment variables.
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.TextUnmarshallerHookFunc(),
+			),
+			WeaklyTypedInput: true,
+		},
+	})
 	if err != nil {
-		logger.Fatal().Err(err).Msg("could not unmarshal main config")
+		return nil, fmt.Errorf("could not unmarshal config: %w", err)
 	}
 
-	validate := validator.New()
+	if mainConfig.Server.RateLimit == 0 {
+		mainConfig.Server.RateLimit = DefaultRateLimit
+	}
 
-	err = validate.Struct(mainConfig)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("config validation failed")
+	if mainConfig.Trash.Retention == 0 {
+		mainConfig.Trash.Retention = DefaultTrashRetention
+	}
+	if mainConfig.Audit.Retention == 0 {
+		mainConfig.Audit.Retention = DefaultAuditRetention
 	}
 
 	// Set default observability config if not provided
@@ -92,9 +215,8 @@ func LoadConfig() (*Config, error) {
 	mainConfig.Observability.ServiceName = "starter"
 	mainConfig.Observability.Environment = mainConfig.Primary.Env
 
-	// Validate observability config
-	if err := mainConfig.Observability.Validate(); err != nil {
-		logger.Fatal().Err(err).Msg("invalid observability config")
+	if err := mainConfig.Validate(); err != nil {
+		return nil, err
 	}
 
 	return mainConfig, nil
@@ -0,0 +1,375 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// baseYAML holds every required setting, so tests only add what they check.
+const baseYAML = `
+server:
+  port: "8080"
+  read_timeout: 30
+  write_timeout: 30
+  idle_timeout: 60
+database:
+  host: localhost
+  port: 5432
+  user: postgres
+  name: main
+  ssl_mode: disable
+  max_open_conns: 25
+  conn_max_lifetime: 300
+  conn_max_idle_time: 300
+`
+
+// useConfigDir runs the test in a fresh directory holding the given files,
+// with no MAIN_ variables or CONFIG_FILE from the outer environment.
+func useConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, EnvPrefix) || name == ConfigFileEnv {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func TestLoadConfigCommaSeparatedModules(t *testing.T) {
+	env := map[string]string{
+		"MAIN_PRIMARY.ENV":                 "test",
+		"MAIN_SERVER.PORT":                 "8080",
+		"MAIN_SERVER.READ_TIMEOUT":         "30",
+		"MAIN_SERVER.WRITE_TIMEOUT":        "30",
+		"MAIN_SERVER.IDLE_TIMEOUT":         "60",
+		"MAIN_DATABASE.HOST":               "localhost",
+		"MAIN_DATABASE.PORT":               "5432",
+		"MAIN_DATABASE.USER":               "postgres",
+		"MAIN_DATABASE.NAME":               "main",
+		"MAIN_DATABASE.SSL_MODE":           "disable",
+		"MAIN_DATABASE.MAX_OPEN_CONNS":     "25",
+		"MAIN_DATABASE.CONN_MAX_LIFETIME":  "300",
+		"MAIN_DATABASE.CONN_MAX_IDLE_TIME": "300",
+		"MAIN_GATEWAY.MODULES":             "task,todo",
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if want := []string{"task", "todo"}; !slices.Equal(cfg.Gateway.Modules, want) {
+		t.Errorf("gateway.modules = %q, want %q", cfg.Gateway.Modules, want)
+	}
+}
+
+func TestLoadConfigLayerPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		overlay    string
+		env        map[string]string
+		want       string
+		wantSource string
+	}{
+		{
+			name:       "file",
+			file:       "primary:\n  env: test\n",
+			want:       "8080",
+			wantSource: "file:config.yaml",
+		},
+		{
+			name:       "overlay over file",
+			file:       "primary:\n  env: test\n",
+			overlay:    "server:\n  port: \"8081\"\n",
+			want:       "8081",
+			wantSource: "file:config.test.yaml",
+		},
+		{
+			name:       "env selects the overlay",
+			file:       "primary:\n  env: other\n",
+			overlay:    "server:\n  port: \"8081\"\n",
+			env:        map[string]string{"MAIN_PRIMARY.ENV": "test"},
+			want:       "8081",
+			wantSource: "file:config.test.yaml",
+		},
+		{
+			name:       "overlay of another environment is ignored",
+			file:       "primary:\n  env: other\n",
+			overlay:    "server:\n  port: \"8081\"\n",
+			want:       "8080",
+			wantSource: "file:config.yaml",
+		},
+		{
+			name:       "env over file and overlay",
+			file:       "primary:\n  env: test\n",
+			overlay:    "server:\n  port: \"8081\"\n",
+			env:        map[string]string{"MAIN_SERVER.PORT": "8082"},
+			want:       "8082",
+			wantSource: "env:MAIN_SERVER.PORT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"config.yaml": baseYAML + tt.file}
+			if tt.overlay != "" {
+				files["config.test.yaml"] = tt.overlay
+			}
+			useConfigDir(t, files)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Server.Port != tt.want {
+				t.Errorf("server.port = %q, want %q", cfg.Server.Port, tt.want)
+			}
+			if got := cfg.Source("server.port"); got != tt.wantSource {
+				t.Errorf("source = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		want       string
+		wantSource string
+	}{
+		{
+			name:       "env _FILE over file",
+			file:       "integration:\n  resend_api_key: from-file\n",
+			env:        map[string]string{"MAIN_INTEGRATION.RESEND_API_KEY_FILE": "secret"},
+			want:       "from-secret",
+			wantSource: "file:secret (via env:MAIN_INTEGRATION.RESEND_API_KEY_FILE)",
+		},
+		{
+			name:       "file _file under env",
+			file:       "integration:\n  resend_api_key_file: secret\n",
+			env:        map[string]string{"MAIN_INTEGRATION.RESEND_API_KEY": "from-env"},
+			want:       "from-env",
+			wantSource: "env:MAIN_INTEGRATION.RESEND_API_KEY",
+		},
+		{
+			name:       "file _file",
+			file:       "integration:\n  resend_api_key_file: secret\n",
+			want:       "from-secret",
+			wantSource: "file:secret (via file:config.yaml)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigDir(t, map[string]string{
+				"config.yaml": baseYAML + "primary:\n  env: test\n" + tt.file,
+				"secret":      "from-secret\n",
+			})
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if got := string(cfg.Integration.ResendAPIKey); got != tt.want {
+				t.Errorf("integration.resend_api_key = %q, want %q", got, tt.want)
+			}
+			if got := cfg.Source("integration.resend_api_key"); got != tt.wantSource {
+				t.Errorf("source = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsValueAndFileInOneLayer(t *testing.T) {
+	useConfigDir(t, map[string]string{
+		"config.yaml": baseYAML + "primary:\n  env: test\n",
+		"secret":      "from-secret",
+	})
+	t.Setenv("MAIN_INTEGRATION.RESEND_API_KEY", "from-env")
+	t.Setenv("MAIN_INTEGRATION.RESEND_API_KEY_FILE", "secret")
+
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "both env:MAIN_INTEGRATION.RESEND_API_KEY and env:MAIN_INTEGRATION.RESEND_API_KEY_FILE are set") {
+		t.Fatalf("err = %v, want both set", err)
+	}
+}
+
+func TestValidationErrorEnvNames(t *testing.T) {
+	useConfigDir(t, map[string]string{
+		"config.yaml": strings.Replace(baseYAML, "  host: localhost\n", "", 1) + `
+primary:
+  env: test
+observability:
+  logging:
+    level: loud
+    format: json
+  health_checks:
+    interval: 30s
+    timeout: 5s
+`,
+	})
+	t.Setenv("MAIN_SERVER.READ_TIMEOUT", "0")
+
+	_, err := LoadConfig()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want *ValidationError", err)
+	}
+
+	tests := []struct {
+		key        string
+		wantEnv    string
+		wantSource string
+	}{
+		{"database.host", "MAIN_DATABASE.HOST", ""},
+		{"server.read_timeout", "MAIN_SERVER.READ_TIMEOUT", "env:MAIN_SERVER.READ_TIMEOUT"},
+		{"observability.logging.level", "MAIN_OBSERVABILITY.LOGGING.LEVEL", "file:config.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var field *FieldError
+			for i := range verr.Fields {
+				if verr.Fields[i].Key == tt.key {
+					field = &verr.Fields[i]
+				}
+			}
+			if field == nil {
+				t.Fatalf("no error for %s in %v", tt.key, verr.Fields)
+			}
+			if field.Env != tt.wantEnv || field.Source != tt.wantSource {
+				t.Errorf("got env %q source %q, want env %q source %q", field.Env, field.Source, tt.wantEnv, tt.wantSource)
+			}
+			if !strings.Contains(verr.Error(), tt.wantEnv+": ") {
+				t.Errorf("error message does not name %s:\n%s", tt.wantEnv, verr.Error())
+			}
+		})
+	}
+
+	if len(verr.Fields) != len(tests) {
+		t.Errorf("got %d errors, want %d: %v", len(verr.Fields), len(tests), verr.Fields)
+	}
+}
+
+func TestResolveFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	password := write("password", "s3cret \r\n")
+	apiKey := write("api_key", "key")
+
+	tests := []struct {
+		name       string
+		values     map[string]string
+		key        string
+		want       string
+		wantSource string
+		wantErr    string
+	}{
+		{
+			name:       "reads file and trims newline only",
+			values:     map[string]string{"database.password_file": password},
+			key:        "database.password",
+			want:       "s3cret ",
+			wantSource: "file:" + password + " (via layer:database.password_file)",
+		},
+		{
+			name:       "trims the path",
+			values:     map[string]string{"integration.resend_api_key_file": " " + apiKey + "\n"},
+			key:        "integration.resend_api_key",
+			want:       "key",
+			wantSource: "file:" + apiKey + " (via layer:integration.resend_api_key_file)",
+		},
+		{
+			name:       "other keys keep their source",
+			values:     map[string]string{"database.password_file": password, "database.host": "db"},
+			key:        "database.host",
+			want:       "db",
+			wantSource: "layer:database.host",
+		},
+		{
+			name:       "bare suffix is a plain key",
+			values:     map[string]string{"_file": "x"},
+			key:        "_file",
+			want:       "x",
+			wantSource: "layer:_file",
+		},
+		{
+			name:    "value and file both set",
+			values:  map[string]string{"database.password": "inline", "database.password_file": password},
+			wantErr: "both layer:database.password and layer:database.password_file are set",
+		},
+		{
+			name:    "missing file",
+			values:  map[string]string{"database.password_file": filepath.Join(dir, "missing")},
+			wantErr: "could not read database.password from layer:database.password_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layer := koanf.New(".")
+			for key, value := range tt.values {
+				if err := layer.Set(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			source, err := resolveFiles(layer, func(key string) string { return "layer:" + key })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFiles: %v", err)
+			}
+
+			if got := layer.String(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+			if got := source(tt.key); got != tt.wantSource {
+				t.Errorf("source = %q, want %q", got, tt.wantSource)
+			}
+			for _, key := range layer.Keys() {
+				if key != "_file" && strings.HasSuffix(key, FileSuffix) {
+					t.Errorf("%s was not removed", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a single resolved config value.
+type Entry struct {
+	Key    string `json:"key"`
+	Env    string `json:"env"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+
+	// raw is the unredacted value, used to detect changed secrets.
+	raw string
+}
+
+// Entries flattens the resolved config into sorted key/value pairs, one per
+// leaf field. Secret values are redacted.
+func (c *Config) Entries() []Entry {
+	var entries []Entry
+	c.collect(reflect.ValueOf(c).Elem(), "", &entries)
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+func (c *Config) collect(v reflect.Value, key string, entries *[]Entry) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+			name = strings.TrimSpace(name)
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			c.collect(v.Field(i), join(key, name), entries)
+		}
+	case v.Kind() == reflect.Map:
+		keys := v.MapKeys()
+		for _, k := range keys {
+			c.collect(v.MapIndex(k), join(key, fmt.Sprint(k.Interface())), entries)
+		}
+	default:
+		entry := Entry{
+			Key:    key,
+			Env:    EnvName(key),
+			Value:  formatValue(v),
+			Source: c.Source(key),
+		}
+		entry.raw = entry.Value
+		if secret, ok := v.Interface().(Secret); ok {
+			entry.raw = secret.Value()
+		}
+		*entries = append(*entries, entry)
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -43,9 +43,13 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 	}
 }
 
+// Validate reports every invalid field as a *ValidationError, with keys
+// relative to the observability section.
 func (c *ObservabilityConfig) Validate() error {
+	verr := &ValidationError{}
+
 	if c.ServiceName == "" {
-		return fmt.Errorf("service_name is required")
+		verr.add("service_name", "is required")
 	}
 
 	// Validate log level
@@ -53,15 +57,15 @@ func (c *ObservabilityConfig) Validate() error {
 		"debug": true, "info": true, "warn": true, "error": true,
 	}
 	if !validLevels[c.Logging.Level] {
-		return fmt.Errorf("invalid logging level: %s (must be one of: debug, info, warn, error)", c.Logging.Level)
+		verr.add("logging.level", fmt.Sprintf("invalid logging level: %s (must be one of: debug, info, warn, error)", c.Logging.Level))
 	}
 
 	// Validate slow query threshold
 	if c.Logging.SlowQueryThreshold < 0 {
-		return fmt.Errorf("logging slow_query_threshold must be non-negative")
+		verr.add("logging.slow_query_threshold", "must be non-negative")
 	}
 
-	return nil
+	return verr.orNil()
 }
 
 func (c *ObservabilityConfig) GetLogLevel() string {
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ReloadableKeys are the settings applied to a running process on reload.
+// Changes to any other key are reported and only take effect on restart.
+var ReloadableKeys = []string{
+	"observability.logging.level",
+	"server.cors_allowed_origins",
+	"server.rate_limit",
+	"server.rate_limit_burst",
+}
+
+// applyReloadable copies the settings listed in ReloadableKeys.
+func applyReloadable(dst, src *Config) {
+	dst.Observability.Logging.Level = src.Observability.Logging.Level
+	dst.Server.CORSAllowedOrigins = src.Server.CORSAllowedOrigins
+	dst.Server.RateLimit = src.Server.RateLimit
+	dst.Server.RateLimitBurst = src.Server.RateLimitBurst
+}
+
+// Change is a config key whose value differs after a reload. Secret values
+// are redacted.
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ReloadResult lists the changes applied by a reload and the changes that
+// need a restart.
+type ReloadResult struct {
+	Applied         []Change `json:"applied"`
+	RestartRequired []Change `json:"restart_required"`
+}
+
+// Subscriber is notified after a reload applied at least one change.
+type Subscriber func(old, current *Config)
+
+type subscription struct {
+	name string
+	fn   Subscriber
+}
+
+// Reloader owns the live config of a running process. Reload re-reads the
+// config layers and swaps in a new Config carrying the reloadable settings;
+// subscribers such as middleware and the logger then pick up the new values.
+type Reloader struct {
+	mu      sync.Mutex
+	current atomic.Pointer[Config]
+	subs    []subscription
+}
+
+func NewReloader(cfg *Config) *Reloader {
+	r := &Reloader{}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the live config. The returned value must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Subscribe registers fn to be called, in registration order, after every
+// reload that applied changes.
+func (r *Reloader) Subscribe(name string, fn Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subs = append(r.subs, subscription{name: name, fn: fn})
+}
+
+// Reload loads and validates the config again. Nothing is applied when the
+// new config is invalid; otherwise all reloadable changes are applied at once.
+func (r *Reloader) Reload() (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	old := r.current.Load()
+	result := &ReloadResult{Applied: []Change{}, RestartRequired: []Change{}}
+
+	for _, change := range diff(old.Entries(), next.Entries()) {
+		if slices.Contains(ReloadableKeys, change.Key) {
+			result.Applied = append(result.Applied, change)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, change)
+		}
+	}
+
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	updated := *old
+	observability := *old.Observability
+	updated.Observability = &observability
+	applyReloadable(&updated, next)
+
+	updated.sources = maps.Clone(old.sources)
+	for _, key := range ReloadableKeys {
+		if source, ok := next.sources[key]; ok {
+			updated.sources[key] = source
+		} else {
+			delete(updated.sources, key)
+		}
+	}
+
+	r.current.Store(&updated)
+	for _, sub := range r.subs {
+		sub.fn(old, &updated)
+	}
+
+	return result, nil
+}
+
+// diff compares two sorted entry lists by key.
+func diff(before, after []Entry) []Change {
+	previous := make(map[string]Entry, len(before))
+	for _, e := range before {
+		previous[e.Key] = e
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(after))
+	for _, e := range after {
+		seen[e.Key] = true
+		if old, ok := previous[e.Key]; !ok || old.raw != e.raw {
+			changes = append(changes, Change{Key: e.Key, Old: old.Value, New: e.Value})
+		}
+	}
+	for _, e := range before {
+		if !seen[e.Key] {
+			changes = append(changes, Change{Key: e.Key, Old: e.Value})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return changes
+}
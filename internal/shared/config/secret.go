@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Redacted replaces the value of secret config fields in printed output.
+const Redacted = "[redacted]"
+
+// Secret is a config value that must never be printed. It formats, logs and
+// marshals as Redacted; Value returns the actual secret.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Redact replaces every secret value of the config found in s, such as in a
+// connection error, with Redacted.
+func (c *Config) Redact(s string) string {
+	for _, secret := range c.secrets() {
+		s = strings.ReplaceAll(s, secret, Redacted)
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			s = strings.ReplaceAll(s, escaped, Redacted)
+		}
+	}
+	return s
+}
+
+// secrets returns the non-empty Secret values of the config.
+func (c *Config) secrets() []string {
+	var values []string
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.String:
+			if s, ok := v.Interface().(Secret); ok && s != "" {
+				values = append(values, s.Value())
+			}
+		}
+	}
+	walk(reflect.ValueOf(c))
+	return values
+}
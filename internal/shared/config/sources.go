@@ -0,0 +1,220 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/toml/v2"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+const (
+	// EnvPrefix prefixes every environment variable read into the config.
+	EnvPrefix = "MAIN_"
+	// ConfigFileEnv points at the base config file. When unset, the first of
+	// DefaultConfigFiles found in the working directory is used, if any.
+	ConfigFileEnv = "CONFIG_FILE"
+	// FileSuffix marks a key whose value is the path of a file holding the
+	// actual value, as mounted by Docker and Kubernetes secrets:
+	// MAIN_DATABASE.PASSWORD_FILE=/run/secrets/db_password sets
+	// database.password. It works in every layer.
+	FileSuffix = "_file"
+)
+
+// DefaultConfigFiles are the base config files looked up when ConfigFileEnv
+// is not set.
+var DefaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Sources records which layer set each config key, e.g.
+// "file:config.production.yaml" or "env:MAIN_SERVER.PORT".
+type Sources map[string]string
+
+// loadLayers reads the base config file, the overlay for the environment and
+// the MAIN_ environment variables, each overriding the previous one. The
+// environment is taken from MAIN_PRIMARY.ENV, falling back to primary.env in
+// the base file, and selects the overlay next to the base file:
+// config.yaml is followed by config.<env>.yaml.
+func loadLayers() (*koanf.Koanf, Sources, error) {
+	envLayer, envNames, err := loadEnv()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load env variables: %w", err)
+	}
+
+	// Resolve env files up front, primary.env may come from one.
+	envSource, err := resolveFiles(envLayer, func(key string) string {
+		return "env:" + envNames[key]
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	base, err := baseConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k := koanf.New(".")
+	sources := make(Sources)
+
+	merge := func(layer *koanf.Koanf, source func(key string) string) error {
+		source, err := resolveFiles(layer, source)
+		if err != nil {
+			return err
+		}
+		for _, key := range layer.Keys() {
+			sources[key] = source(key)
+		}
+		return k.Merge(layer)
+	}
+
+	if base != "" {
+		layer, err := loadFile(base)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := merge(layer, fileSource(base)); err != nil {
+			return nil, nil, err
+		}
+
+		environment := envLayer.String("primary.env")
+		if environment == "" {
+			environment = layer.String("primary.env")
+		}
+
+		if overlay := overlayFile(base, environment); overlay != "" {
+			layer, err := loadFile(overlay)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := merge(layer, fileSource(overlay)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if err := merge(envLayer, envSource); err != nil {
+		return nil, nil, err
+	}
+
+	return k, sources, nil
+}
+
+// resolveFiles replaces every "<key>_file" entry of a layer with "<key>" set
+// to the contents of that file, without the trailing newline. The returned
+// source reports resolved keys as "file:<path> (via <source>)".
+func resolveFiles(layer *koanf.Koanf, source func(key string) string) (func(key string) string, error) {
+	resolved := make(map[string]string)
+
+	for _, fileKey := range layer.Keys() {
+		key, ok := strings.CutSuffix(fileKey, FileSuffix)
+		if !ok || key == "" {
+			continue
+		}
+		if layer.String(key) != "" {
+			return nil, fmt.Errorf("both %s and %s are set", source(key), source(fileKey))
+		}
+
+		path := strings.TrimSpace(layer.String(fileKey))
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s from %s: %w", key, source(fileKey), err)
+		}
+
+		value := strings.TrimRight(string(content), "\r\n")
+		if err := layer.Set(key, value); err != nil {
+			return nil, err
+		}
+		layer.Delete(fileKey)
+		resolved[key] = fmt.Sprintf("file:%s (via %s)", path, source(fileKey))
+	}
+
+	return func(key string) string {
+		if s, ok := resolved[key]; ok {
+			return s
+		}
+		return source(key)
+	}, nil
+}
+
+// loadEnv reads the MAIN_ environment variables and remembers the variable
+// name behind each key.
+func loadEnv() (*koanf.Koanf, map[string]string, error) {
+	names := make(map[string]string)
+
+	k := koanf.New(".")
+	err := k.Load(env.ProviderWithValue(EnvPrefix, ".", func(name, value string) (string, any) {
+		key := strings.ToLower(strings.TrimPrefix(name, EnvPrefix))
+		names[key] = name
+		return key, value
+	}), nil)
+
+	return k, names, err
+}
+
+func loadFile(path string) (*koanf.Koanf, error) {
+	var parser koanf.Parser
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parser = yaml.Parser()
+	case ".toml":
+		parser = toml.Parser()
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	k := koanf.New(".")
+	if err := k.Load(file.Provider(path), parser); err != nil {
+		return nil, fmt.Errorf("could not load config file %s: %w", path, err)
+	}
+	return k, nil
+}
+
+// baseConfigFile returns the base config file, or an empty path when there is
+// none. A file named explicitly through ConfigFileEnv must exist.
+func baseConfigFile() (string, error) {
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file from %s: %w", ConfigFileEnv, err)
+		}
+		return path, nil
+	}
+
+	for _, path := range DefaultConfigFiles {
+		if exists(path) {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// overlayFile returns the environment overlay of base, if it exists.
+func overlayFile(base, environment string) string {
+	if environment == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(base)
+	path := strings.TrimSuffix(base, ext) + "." + environment + ext
+	if !exists(path) {
+		return ""
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+func fileSource(path string) func(string) string {
+	return func(string) string {
+		return "file:" + path
+	}
+}
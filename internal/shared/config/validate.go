@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is a config value that failed validation.
+type FieldError struct {
+	// Key is the config key, e.g. "database.host".
+	Key string `json:"key"`
+	// Env is the environment variable that sets the key.
+	Env string `json:"env"`
+	// Source is the layer that set the current value, if any.
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError gathers every config value that failed validation.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config (%d problems):", len(e.Fields))
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, "\n  %s: %s", f.Env, f.Message)
+		if f.Source != "" {
+			fmt.Fprintf(&b, " (set by %s)", f.Source)
+		}
+	}
+	return b.String()
+}
+
+func (e *ValidationError) add(key, message string) {
+	e.Fields = append(e.Fields, FieldError{Key: key, Env: EnvName(key), Message: message})
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// EnvName returns the environment variable that sets a config key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// Validate checks the whole config and reports every failed field at once.
+func (c *Config) Validate() error {
+	verr := &ValidationError{}
+
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("koanf"), ",")
+		if name == "-" {
+			return ""
+		}
+		return strings.TrimSpace(name)
+	})
+
+	if err := validate.Struct(c); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		for _, fe := range fieldErrs {
+			// Namespaces start with the struct name: "Config.database.host".
+			_, key, _ := strings.Cut(fe.Namespace(), ".")
+			verr.add(key, fieldMessage(fe))
+		}
+	}
+
+	if c.Observability != nil {
+		var oerr *ValidationError
+		if err := c.Observability.Validate(); errors.As(err, &oerr) {
+			for _, f := range oerr.Fields {
+				verr.add("observability."+f.Key, f.Message)
+			}
+		}
+	}
+
+	for i := range verr.Fields {
+		verr.Fields[i].Source = c.Source(verr.Fields[i].Key)
+	}
+
+	return verr.orNil()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must not exceed %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		if fe.Param() != "" {
+			return fmt.Sprintf("failed %s=%s", fe.Tag(), fe.Param())
+		}
+		return fmt.Sprintf("failed %s", fe.Tag())
+	}
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	tern "github.com/jackc/tern/v2/migrate"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rs/zerolog"
+)
+
+// ChecksumTable records the checksum and SQL of every applied migration, so
+// a migration file edited after it ran is caught instead of silently skipped.
+const ChecksumTable = "public.schema_migration_checksums"
+
+// Drift is an applied migration whose file no longer matches what ran.
+type Drift struct {
+	Version int32  `json:"version"`
+	Name    string `json:"name"`
+	Diff    string `json:"diff"`
+}
+
+// DriftError stops a migration run while applied migrations have drifted.
+type DriftError struct {
+	Project string
+	Drifts  []Drift
+}
+
+func (e *DriftError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d applied migration(s) of %q changed since they ran; restore the files and put changes in a new migration:",
+		len(e.Drifts), e.Project)
+	for _, d := range e.Drifts {
+		fmt.Fprintf(&b, "\n\n%s", d.Diff)
+	}
+	return b.String()
+}
+
+// appliedMigration is a row of ChecksumTable.
+type appliedMigration struct {
+	name     string
+	checksum string
+	sql      string
+}
+
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// lock takes the session advisory lock of the project, waiting while another
+// process migrates it, so replicas starting together migrate one at a time.
+func (m *Migrator) lock(ctx context.Context) (unlock func(), err error) {
+	key := "migrations:" + m.Project.SchemaTable
+	if _, err := m.conn.Exec(ctx, "SELECT pg_advisory_lock(hashtextextended($1, 0))", key); err != nil {
+		return nil, fmt.Errorf("acquiring migration lock for %q: %w", m.Project.Name, err)
+	}
+	return func() {
+		_, _ = m.conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock(hashtextextended($1, 0))", key)
+	}, nil
+}
+
+func (m *Migrator) ensureChecksumTable(ctx context.Context) error {
+	// Projects lock separately, so serialise the creation itself.
+	return pgx.BeginFunc(ctx, m.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended('migrations', 0))"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			CREATE TABLE IF NOT EXISTS `+ChecksumTable+` (
+				project TEXT NOT NULL,
+				version INT4 NOT NULL,
+				name TEXT NOT NULL,
+				checksum TEXT NOT NULL,
+				up_sql TEXT NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (project, version)
+			)`)
+		return err
+	})
+}
+
+// applied loads the recorded migrations of the project by version. It is
+// empty before the first checksummed run.
+func (m *Migrator) applied(ctx context.Context) (map[int32]appliedMigration, error) {
+	var table *string
+	if err := m.conn.QueryRow(ctx, "SELECT to_regclass($1)::text", ChecksumTable).Scan(&table); err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", ChecksumTable, err)
+	}
+	if table == nil {
+		return nil, nil
+	}
+
+	rows, err := m.conn.Query(ctx, `
+		SELECT version, name, checksum, up_sql
+		FROM `+ChecksumTable+`
+		WHERE project = $1`, m.Project.Name)
+	if err != nil {
+		return nil, fmt.Errorf("loading migration checksums for %q: %w", m.Project.Name, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int32]appliedMigration)
+	for rows.Next() {
+		var version int32
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.sql); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// Drifts compares the applied migrations up to current with the embedded
+// files. Migrations applied before checksums were recorded have nothing to
+// compare against and are returned as unrecorded.
+func (m *Migrator) Drifts(ctx context.Context, current int32) ([]Drift, []*tern.Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var drifts []Drift
+	var unrecorded []*tern.Migration
+	for _, migration := range m.migrations[:min(max(current, 0), m.Latest())] {
+		a, ok := applied[migration.Sequence]
+		if !ok {
+			unrecorded = append(unrecorded, migration)
+			continue
+		}
+		if a.checksum == checksum(migration.UpSQL) {
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(a.sql),
+			B:        difflib.SplitLines(migration.UpSQL),
+			FromFile: fmt.Sprintf("applied %s (version %d)", a.name, migration.Sequence),
+			ToFile:   path.Join("migrations", m.Project.dir, migration.Name),
+			Context:  3,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		drifts = append(drifts, Drift{Version: migration.Sequence, Name: migration.Name, Diff: diff})
+	}
+	return drifts, unrecorded, nil
+}
+
+// verify fails with a DriftError if applied migrations changed, and records
+// the checksums of applied migrations that have none yet, trusting the files
+// as they are now.
+func (m *Migrator) verify(ctx context.Context, logger *zerolog.Logger, current int32) error {
+	drifts, unrecorded, err := m.Drifts(ctx, current)
+	if err != nil {
+		return err
+	}
+	if len(drifts) > 0 {
+		return &DriftError{Project: m.Project.Name, Drifts: drifts}
+	}
+
+	for _, migration := range unrecorded {
+		if err := m.recordChecksum(ctx, migration); err != nil {
+			return err
+		}
+	}
+	if len(unrecorded) > 0 {
+		logger.Info().
+			Str("project", m.Project.Name).
+			Int("migrations", len(unrecorded)).
+			Msg("recorded checksums of previously applied migrations")
+	}
+	return nil
+}
+
+func (m *Migrator) recordChecksum(ctx context.Context, migration *tern.Migration) error {
+	_, err := m.conn.Exec(ctx, `
+		INSERT INTO `+ChecksumTable+` (project, version, name, checksum, up_sql)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project, version) DO UPDATE
+		SET name = EXCLUDED.name,
+			checksum = EXCLUDED.checksum,
+			up_sql = EXCLUDED.up_sql,
+			applied_at = CURRENT_TIMESTAMP`,
+		m.Project.Name, migration.Sequence, migration.Name, checksum(migration.UpSQL), migration.UpSQL)
+	if err != nil {
+		return fmt.Errorf("recording checksum of %s: %w", migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) deleteChecksum(ctx context.Context, version int32) error {
+	_, err := m.conn.Exec(ctx, "DELETE FROM "+ChecksumTable+" WHERE project = $1 AND version = $2", m.Project.Name, version)
+	if err != nil {
+		return fmt.Errorf("removing checksum of version %d: %w", version, err)
+	}
+	return nil
+}
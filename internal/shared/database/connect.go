@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/rs/zerolog"
+)
+
+// Defaults for the startup connection retry when DatabaseConfig leaves the
+// settings at zero.
+const (
+	DefaultConnectAttempts   = 10
+	DefaultConnectBackoff    = time.Second
+	DefaultConnectMaxBackoff = 30 * time.Second
+)
+
+// DSN builds the connection URL for the configured database.
+func DSN(cfg config.DatabaseConfig) string {
+	hostPort := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+	// URL-encode the password
+	encodedPassword := url.QueryEscape(cfg.Password.Value())
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		cfg.User,
+		encodedPassword,
+		hostPort,
+		cfg.Name,
+		cfg.SSLMode,
+	)
+}
+
+// retryConnect runs connect until it succeeds, backing off exponentially
+// between attempts, so the app waits for Postgres instead of crash-looping
+// while it starts.
+func retryConnect(ctx context.Context, logger *zerolog.Logger, cfg config.DatabaseConfig, connect func(ctx context.Context) error) error {
+	attempts := cfg.ConnectAttempts
+	if attempts <= 0 {
+		attempts = DefaultConnectAttempts
+	}
+	backoff := cfg.ConnectBackoff
+	if backoff <= 0 {
+		backoff = DefaultConnectBackoff
+	}
+	maxBackoff := cfg.ConnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultConnectMaxBackoff
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = connect(ctx); err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Int("max_attempts", attempts).
+			Dur("retry_in", backoff).
+			Msg("database not reachable, retrying")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
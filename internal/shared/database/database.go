@@ -3,14 +3,13 @@ package database
 import (
 	"context"
 	"fmt"
-	"net"
-	"net/url"
-	"strconv"
 	"time"
 
 	"github.com/goku-m/main/internal/shared/config"
 	loggerConfig "github.com/goku-m/main/internal/shared/logger"
 	pgxzero "github.com/jackc/pgx-zerolog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
 	"github.com/rs/zerolog"
@@ -18,61 +17,132 @@ import (
 
 type Database struct {
 	Pool *pgxpool.Pool
-	log  *zerolog.Logger
+	// Replica is the read replica pool, nil when none is configured or it
+	// was unreachable at startup. Use Reader rather than reading it directly.
+	Replica *pgxpool.Pool
+	Tracer  *QueryTracer
+	log     *zerolog.Logger
+
+	// Set on module databases created by ForSchema.
+	scope *schemaScope
+	// beginTx begins the transactions of InTx; nil begins them on Pool.
+	// Tests replace it to run without a database.
+	beginTx func(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
 }
 
 const DatabasePingTimeout = 10
 
 func New(cfg *config.Config, logger *zerolog.Logger) (*Database, error) {
-	hostPort := net.JoinHostPort(cfg.Database.Host, strconv.Itoa(cfg.Database.Port))
-
-	// URL-encode the password
-	encodedPassword := url.QueryEscape(cfg.Database.Password)
-	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
-		cfg.Database.User,
-		encodedPassword,
-		hostPort,
-		cfg.Database.Name,
-		cfg.Database.SSLMode,
-	)
-
-	pgxPoolConfig, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
+	var slowQueryThreshold time.Duration
+	if cfg.Observability != nil {
+		slowQueryThreshold = cfg.Observability.Logging.SlowQueryThreshold
 	}
+	queryTracer := NewQueryTracer(logger, slowQueryThreshold)
 
+	var tracer pgx.QueryTracer = queryTracer
 	if cfg.Primary.Env == "local" {
 		globalLevel := logger.GetLevel()
 		pgxLogger := loggerConfig.NewPgxLogger(globalLevel)
-		pgxPoolConfig.ConnConfig.Tracer = &tracelog.TraceLog{
+		tracer = multitracer.New(queryTracer, &tracelog.TraceLog{
 			Logger:   pgxzero.NewLogger(pgxLogger),
 			LogLevel: tracelog.LogLevel(loggerConfig.GetPgxTraceLogLevel(globalLevel)),
+		})
+	}
+
+	pool, err := connectPool(logger, cfg.Database, tracer)
+	if err != nil {
+		return nil, err
+	}
+
+	database := &Database{
+		Pool:   pool,
+		Tracer: queryTracer,
+		log:    logger,
+	}
+
+	// A replica that cannot be reached is not fatal: reads fall back to the
+	// primary.
+	if replicaConfig, ok := cfg.Database.ReplicaDatabase(); ok {
+		replica, err := connectPool(logger, replicaConfig, tracer)
+		if err != nil {
+			logger.Error().Err(err).Msg("read replica unavailable, reading from the primary")
+		} else {
+			database.Replica = replica
 		}
 	}
 
+	return database, nil
+}
+
+// connectPool creates a pool for cfg and waits until the database answers.
+func connectPool(logger *zerolog.Logger, cfg config.DatabaseConfig, tracer pgx.QueryTracer) (*pgxpool.Pool, error) {
+	pgxPoolConfig, err := pgxpool.ParseConfig(DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
+	}
+	applyPoolSettings(pgxPoolConfig, cfg)
+	if cfg.MaxIdleConns > 0 {
+		logger.Warn().
+			Str("host", cfg.Host).
+			Msg("database.max_idle_conns is deprecated and ignored, idle connections close after conn_max_idle_time")
+	}
+	pgxPoolConfig.ConnConfig.Tracer = tracer
+	pgxPoolConfig.BeforeAcquire = setSearchPath
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), pgxPoolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
 	}
 
-	database := &Database{
-		Pool: pool,
-		log:  logger,
+	err = retryConnect(context.Background(), logger, cfg, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, DatabasePingTimeout*time.Second)
+		defer cancel()
+		return pool.Ping(ctx)
+	})
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %w", cfg.Host, err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), DatabasePingTimeout*time.Second)
-	defer cancel()
-	if err = pool.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
+	logger.Info().
+		Str("host", cfg.Host).
+		Str("database", cfg.Name).
+		Int32("max_conns", pgxPoolConfig.MaxConns).
+		Int32("min_idle_conns", pgxPoolConfig.MinIdleConns).
+		Msg("connected to the database")
 
-	logger.Info().Msg("connected to the database")
+	return pool, nil
+}
 
-	return database, nil
+// applyPoolSettings maps the pool settings of DatabaseConfig onto pgxpool.
+// The deprecated MaxIdleConns is not applied: idle connections are only
+// closed after ConnMaxIdleTime.
+func applyPoolSettings(poolConfig *pgxpool.Config, cfg config.DatabaseConfig) {
+	if cfg.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.MinIdleConns > 0 {
+		poolConfig.MinIdleConns = int32(min(cfg.MinIdleConns, int(poolConfig.MaxConns)))
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = time.Duration(cfg.ConnMaxLifetime) * time.Second
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = time.Duration(cfg.ConnMaxIdleTime) * time.Second
+	}
 }
 
+// Close closes the pools. Module databases share the pools of the database
+// they were scoped from, so closing one of them does nothing.
 func (db *Database) Close() error {
+	if db.scope != nil {
+		return nil
+	}
+
 	db.log.Info().Msg("closing database connection pool")
 	db.Pool.Close()
+	if db.Replica != nil {
+		db.Replica.Close()
+	}
 	return nil
 }
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestApplyPoolSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.DatabaseConfig
+		wantMax     int32
+		wantMinIdle int32
+	}{
+		{"max idle is not applied", config.DatabaseConfig{MaxOpenConns: 25, MaxIdleConns: 25}, 25, 0},
+		{"min idle", config.DatabaseConfig{MaxOpenConns: 25, MinIdleConns: 5}, 25, 5},
+		{"min idle capped at max", config.DatabaseConfig{MaxOpenConns: 4, MinIdleConns: 10}, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poolConfig, err := pgxpool.ParseConfig("postgres://localhost/db")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.cfg.ConnMaxLifetime = 300
+			tt.cfg.ConnMaxIdleTime = 60
+
+			applyPoolSettings(poolConfig, tt.cfg)
+
+			if poolConfig.MaxConns != tt.wantMax || poolConfig.MinIdleConns != tt.wantMinIdle {
+				t.Errorf("max %d min idle %d, want max %d min idle %d",
+					poolConfig.MaxConns, poolConfig.MinIdleConns, tt.wantMax, tt.wantMinIdle)
+			}
+			if poolConfig.MaxConnLifetime != 300*time.Second || poolConfig.MaxConnIdleTime != time.Minute {
+				t.Errorf("lifetime %s idle time %s", poolConfig.MaxConnLifetime, poolConfig.MaxConnIdleTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/worker"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// Listener settings.
+const (
+	// SubscriptionBuffer is how many notifications a subscriber may fall
+	// behind before further ones are dropped for it.
+	SubscriptionBuffer = 64
+
+	listenerPingInterval = 30 * time.Second
+	listenerMinBackoff   = time.Second
+	listenerMaxBackoff   = 30 * time.Second
+)
+
+// Change is the payload shared.notify_change publishes for a row change.
+type Change struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+}
+
+// ChangeChannel is the channel shared.notify_change publishes the changes of
+// schema.table on.
+func ChangeChannel(schema, table string) string {
+	return schema + "." + table
+}
+
+// Notification is a NOTIFY received by a Listener. After the listener lost
+// its connection, subscribers get one with Missed set and no payload:
+// notifications sent while it was reconnecting are lost, so anything derived
+// from them should be reloaded.
+type Notification struct {
+	Channel string
+	Payload string
+	Missed  bool
+}
+
+// Change decodes a notification sent by shared.notify_change.
+func (n Notification) Change() (Change, error) {
+	var c Change
+	if err := json.Unmarshal([]byte(n.Payload), &c); err != nil {
+		return Change{}, fmt.Errorf("invalid change notification on %q: %w", n.Channel, err)
+	}
+	return c, nil
+}
+
+// Listener holds one dedicated connection to the primary that LISTENs on
+// every channel with a subscriber and fans notifications out to them. It
+// reconnects with backoff when the connection is lost. A subscriber that
+// falls behind loses notifications instead of blocking the others.
+type Listener struct {
+	connConfig *pgx.ConnConfig
+	log        *zerolog.Logger
+
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+	// wake interrupts the wait for notifications to LISTEN or UNLISTEN.
+	wake      chan struct{}
+	connected atomic.Bool
+
+	worker *worker.Worker
+}
+
+// Subscription receives the notifications of one channel on C until it is
+// closed, or the listener is stopped.
+type Subscription struct {
+	C <-chan Notification
+
+	ch       chan Notification
+	channel  string
+	listener *Listener
+	once     sync.Once
+}
+
+// NewListener returns a listener connecting like the primary pool. It does
+// nothing until started.
+func (db *Database) NewListener() *Listener {
+	l := &Listener{
+		connConfig: db.Pool.Config().ConnConfig.Copy(),
+		log:        db.log,
+		subs:       make(map[string]map[*Subscription]struct{}),
+		wake:       make(chan struct{}, 1),
+	}
+	l.worker = worker.New("database listener", l.run)
+	return l
+}
+
+// Subscribe starts receiving the notifications of channel. Notifications
+// only arrive while the listener runs.
+func (l *Listener) Subscribe(channel string) *Subscription {
+	ch := make(chan Notification, SubscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, channel: channel, listener: l}
+
+	l.mu.Lock()
+	if l.subs[channel] == nil {
+		l.subs[channel] = make(map[*Subscription]struct{})
+	}
+	l.subs[channel][sub] = struct{}{}
+	l.mu.Unlock()
+
+	l.notifyChanged()
+	return sub
+}
+
+// Close stops the subscription and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		l := s.listener
+		l.mu.Lock()
+		delete(l.subs[s.channel], s)
+		if len(l.subs[s.channel]) == 0 {
+			delete(l.subs, s.channel)
+		}
+		close(s.ch)
+		l.mu.Unlock()
+
+		l.notifyChanged()
+	})
+}
+
+// Connected reports whether the listener currently holds its connection.
+func (l *Listener) Connected() bool {
+	return l.connected.Load()
+}
+
+// Start launches the listen loop. The context only bounds startup; the loop
+// runs until Stop is called.
+func (l *Listener) Start(ctx context.Context) error {
+	return l.worker.Start(ctx)
+}
+
+// Stop closes the connection and every subscription, and waits for the loop
+// to exit or for ctx to expire.
+func (l *Listener) Stop(ctx context.Context) error {
+	err := l.worker.Stop(ctx)
+
+	l.mu.Lock()
+	var subs []*Subscription
+	for _, channelSubs := range l.subs {
+		for sub := range channelSubs {
+			subs = append(subs, sub)
+		}
+	}
+	l.mu.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+
+	return err
+}
+
+func (l *Listener) notifyChanged() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the listener connected, reconnecting with backoff, until ctx is
+// done.
+func (l *Listener) run(ctx context.Context) {
+	backoff := listenerMinBackoff
+	missed := false
+	for {
+		established, err := l.listen(ctx, missed)
+		l.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if established {
+			missed = true
+			backoff = listenerMinBackoff
+		}
+
+		l.log.Warn().
+			Err(err).
+			Dur("retry_in", backoff).
+			Msg("database listener not connected, retrying")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenerMaxBackoff)
+	}
+}
+
+// listen connects and dispatches notifications until the connection fails
+// or ctx is done. established reports whether it got as far as listening.
+func (l *Listener) listen(ctx context.Context, missed bool) (established bool, err error) {
+	conn, err := pgx.ConnectConfig(ctx, l.connConfig)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	listening := make(map[string]bool)
+	if err := l.sync(ctx, conn, listening); err != nil {
+		return false, err
+	}
+	l.connected.Store(true)
+	l.log.Info().Int("channels", len(listening)).Msg("database listener connected")
+
+	if missed {
+		l.dispatchMissed()
+	}
+
+	for {
+		// Runs after every wake-up, so a subscription change is never lost
+		// even if the wake-up raced with a notification.
+		if err := l.sync(ctx, conn, listening); err != nil {
+			return true, err
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, listenerPingInterval)
+		go func() {
+			select {
+			case <-l.wake:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		n, err := conn.WaitForNotification(waitCtx)
+		interrupted := waitCtx.Err()
+		cancel()
+
+		switch {
+		case err == nil:
+			l.dispatch(Notification{Channel: n.Channel, Payload: n.Payload})
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case errors.Is(interrupted, context.DeadlineExceeded):
+			// Nothing arrived for a while: make sure the connection is alive.
+			if err := conn.Ping(ctx); err != nil {
+				return true, fmt.Errorf("ping failed: %w", err)
+			}
+		case interrupted != nil:
+			// Woken up to sync the subscriptions
+		default:
+			return true, err
+		}
+	}
+}
+
+// sync LISTENs on the channels that gained subscribers and UNLISTENs on the
+// ones that lost them.
+func (l *Listener) sync(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	l.mu.Lock()
+	want := make(map[string]bool, len(l.subs))
+	for channel := range l.subs {
+		want[channel] = true
+	}
+	l.mu.Unlock()
+
+	for channel := range want {
+		if listening[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to listen on %q: %w", channel, err)
+		}
+		listening[channel] = true
+	}
+
+	for channel := range listening {
+		if want[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to unlisten %q: %w", channel, err)
+		}
+		delete(listening, channel)
+	}
+
+	return nil
+}
+
+func (l *Listener) dispatch(n Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for sub := range l.subs[n.Channel] {
+		select {
+		case sub.ch <- n:
+		default:
+			l.log.Warn().
+				Str("channel", n.Channel).
+				Msg("database listener subscriber is behind, dropping notification")
+		}
+	}
+}
+
+// dispatchMissed tells every subscriber that notifications were lost while
+// reconnecting.
+func (l *Listener) dispatchMissed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for channel, channelSubs := range l.subs {
+		for sub := range channelSubs {
+			select {
+			case sub.ch <- Notification{Channel: channel, Missed: true}:
+			default:
+			}
+		}
+	}
+}
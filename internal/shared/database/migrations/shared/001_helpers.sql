@@ -0,0 +1,41 @@
+-- Helpers used by every module schema. Modules have shared on their
+-- search_path, so they call these unqualified.
+CREATE SCHEMA IF NOT EXISTS shared;
+
+CREATE OR REPLACE FUNCTION shared.camel(input_row anyelement)
+    RETURNS jsonb
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    result jsonb := '{}';
+    rec record;
+BEGIN
+    FOR rec IN
+    SELECT
+        lower(substring(regexp_replace(initcap(regexp_replace(key, '_', ' ', 'g')), '\s', '', 'g'), 1, 1)) || substring(regexp_replace(initcap(regexp_replace(key, '_', ' ', 'g')), '\s', '', 'g'), 2) AS camel_key,
+        value
+    FROM
+        jsonb_each(to_jsonb(input_row))
+        LOOP
+            result := result || jsonb_build_object(rec.camel_key, rec.value);
+        END LOOP;
+    RETURN result;
+END;
+$$;
+
+-- Create updated_at trigger function
+CREATE OR REPLACE FUNCTION shared.trigger_set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+---- create above / drop below ----
+
+DROP FUNCTION IF EXISTS shared.trigger_set_updated_at();
+DROP FUNCTION IF EXISTS shared.camel(anyelement);
+
+-- Fails while module triggers still use the helpers.
+DROP SCHEMA IF EXISTS shared;
@@ -0,0 +1,31 @@
+-- Messages written in the same transaction as a module's domain change and
+-- relayed to the job queue afterwards, so side effects survive a Redis
+-- outage. Rows are deleted some time after they were published.
+CREATE TABLE shared.outbox (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    module TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    queue TEXT NOT NULL DEFAULT 'default',
+
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMPTZ
+);
+
+-- Pending messages of a module, oldest first per aggregate
+CREATE INDEX idx_outbox_pending ON shared.outbox(module, aggregate_type, aggregate_id, id)
+    WHERE published_at IS NULL;
+
+-- Cleanup of published messages
+CREATE INDEX idx_outbox_published_at ON shared.outbox(module, published_at)
+    WHERE published_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS shared.outbox;
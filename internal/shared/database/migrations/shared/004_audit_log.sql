@@ -0,0 +1,25 @@
+-- Who changed which row of a module, when, and how. diff maps each changed
+-- field to its value before and after the change.
+CREATE TABLE shared.audit_log (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    module TEXT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    actor TEXT,
+    request_id TEXT,
+    diff JSONB NOT NULL DEFAULT '{}'
+);
+
+-- History of one row, newest first
+CREATE INDEX idx_audit_log_entity ON shared.audit_log(module, entity, entity_id, id);
+-- Changes made by one actor
+CREATE INDEX idx_audit_log_actor ON shared.audit_log(module, actor, id);
+-- Retention cleanup
+CREATE INDEX idx_audit_log_created_at ON shared.audit_log(module, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS shared.audit_log;
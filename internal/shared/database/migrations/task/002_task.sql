@@ -31,3 +31,9 @@ CREATE TRIGGER set_updated_at_tasks
 
 -- Composite index for user tasks with status and priority
 CREATE INDEX idx_tasks_status_priority ON tasks(status, priority);
+
+---- create above / drop below ----
+
+SET search_path TO task, public;
+
+DROP TABLE IF EXISTS tasks;
@@ -0,0 +1,46 @@
+-- Use the helpers of the shared schema instead of this schema's copies.
+DROP TRIGGER IF EXISTS set_updated_at_tasks ON task.tasks;
+CREATE TRIGGER set_updated_at_tasks
+    BEFORE UPDATE ON task.tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION shared.trigger_set_updated_at();
+
+DROP FUNCTION IF EXISTS task.trigger_set_updated_at();
+DROP FUNCTION IF EXISTS task.camel(anyelement);
+
+---- create above / drop below ----
+
+CREATE OR REPLACE FUNCTION task.camel(input_row anyelement)
+    RETURNS jsonb
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    result jsonb := '{}';
+    rec record;
+BEGIN
+    FOR rec IN
+    SELECT
+        lower(substring(regexp_replace(initcap(regexp_replace(key, '_', ' ', 'g')), '\s', '', 'g'), 1, 1)) || substring(regexp_replace(initcap(regexp_replace(key, '_', ' ', 'g')), '\s', '', 'g'), 2) AS camel_key,
+        value
+    FROM
+        jsonb_each(to_jsonb(input_row))
+        LOOP
+            result := result || jsonb_build_object(rec.camel_key, rec.value);
+        END LOOP;
+    RETURN result;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION task.trigger_set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS set_updated_at_tasks ON task.tasks;
+CREATE TRIGGER set_updated_at_tasks
+    BEFORE UPDATE ON task.tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION task.trigger_set_updated_at();
@@ -0,0 +1,9 @@
+-- Publish inserts, updates and deletes on the channel "task.tasks".
+CREATE TRIGGER notify_change_tasks
+    AFTER INSERT OR UPDATE OR DELETE ON task.tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION shared.notify_change();
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS notify_change_tasks ON task.tasks;
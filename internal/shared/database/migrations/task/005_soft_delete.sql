@@ -0,0 +1,12 @@
+-- Deleted tasks stay in the trash, where they can be restored, until they
+-- are purged by hand or after the trash retention period.
+ALTER TABLE task.tasks ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_tasks_deleted_at ON task.tasks(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+---- create above / drop below ----
+
+-- Rows in the trash would otherwise come back as live rows.
+DELETE FROM task.tasks WHERE deleted_at IS NOT NULL;
+ALTER TABLE task.tasks DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+-- Bumped by every update of a task. An update made against an older version
+-- is rejected instead of silently overwriting the newer one.
+ALTER TABLE task.tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+---- create above / drop below ----
+
+ALTER TABLE task.tasks DROP COLUMN IF EXISTS version;
@@ -31,4 +31,15 @@ BEGIN
     NEW.updated_at = CURRENT_TIMESTAMP;
     RETURN NEW;
 END;
-$$ LANGUAGE plpgsql;
\ No newline at end of file
+$$ LANGUAGE plpgsql;
+
+---- create above / drop below ----
+
+SET search_path TO todo, public;
+
+DROP FUNCTION IF EXISTS trigger_set_updated_at();
+DROP FUNCTION IF EXISTS camel(anyelement);
+
+-- Fails while the schema still holds tables, like the ones of later
+-- migrations that were not rolled back.
+DROP SCHEMA IF EXISTS todo;
@@ -32,3 +32,9 @@ CREATE TRIGGER set_updated_at_todos
 
 -- Composite index for user todos with status and priority
 CREATE INDEX idx_todos_status_priority ON todos(status, priority);
+
+---- create above / drop below ----
+
+SET search_path TO todo, public;
+
+DROP TABLE IF EXISTS todos;
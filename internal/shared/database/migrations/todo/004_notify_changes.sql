@@ -0,0 +1,9 @@
+-- Publish inserts, updates and deletes on the channel "todo.todos".
+CREATE TRIGGER notify_change_todos
+    AFTER INSERT OR UPDATE OR DELETE ON todo.todos
+    FOR EACH ROW
+    EXECUTE FUNCTION shared.notify_change();
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS notify_change_todos ON todo.todos;
@@ -0,0 +1,12 @@
+-- Deleted todos stay in the trash, where they can be restored, until they
+-- are purged by hand or after the trash retention period.
+ALTER TABLE todo.todos ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_todos_deleted_at ON todo.todos(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+---- create above / drop below ----
+
+-- Rows in the trash would otherwise come back as live rows.
+DELETE FROM todo.todos WHERE deleted_at IS NOT NULL;
+ALTER TABLE todo.todos DROP COLUMN IF EXISTS deleted_at;
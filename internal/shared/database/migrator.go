@@ -3,11 +3,10 @@ package database
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
-	"net"
-	"net/url"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/goku-m/main/internal/shared/config"
@@ -20,58 +19,138 @@ import (
 //go:embed migrations/**/*.sql
 var migrations embed.FS
 
+// Migration directions reported in MigrationStep.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// versionTableSchema holds the version tables. They are schema-qualified so
+// a search_path set by a migration cannot move them.
+const versionTableSchema = "public"
+
+// MigrationProject is one directory of migrations under migrations/, tracked
+// in its own schema_version_<project> table so each app's schema moves
+// independently of the others.
+type MigrationProject struct {
+	Name        string `json:"project"`
+	SchemaTable string `json:"schema_table"`
+	dir         string
+}
+
+// MigrationStep is a single migration run in one direction.
+type MigrationStep struct {
+	Project   string `json:"project"`
+	Version   int32  `json:"version"`
+	Name      string `json:"name"`
+	Direction string `json:"direction"`
+	SQL       string `json:"sql"`
+}
+
+// MigrationStatus is the version of a project in the database against the
+// migrations embedded in the binary.
+type MigrationStatus struct {
+	MigrationProject
+	Current int32    `json:"current"`
+	Latest  int32    `json:"latest"`
+	Pending []string `json:"pending"`
+	// Drifted lists applied migrations whose file changed since they ran.
+	Drifted []string `json:"drifted"`
+}
+
+// Migrator moves the schema of one project between versions.
+type Migrator struct {
+	Project    MigrationProject
+	conn       *pgx.Conn
+	migrations []*tern.Migration
+}
+
 func Migrate(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) error {
 	return MigrateAll(ctx, logger, cfg)
 }
 
+// MigrateAll applies every pending migration of every project.
 func MigrateAll(ctx context.Context, logger *zerolog.Logger, cfg *config.Config) error {
-	hostPort := net.JoinHostPort(cfg.Database.Host, strconv.Itoa(cfg.Database.Port))
-
-	// URL-encode the password
-	encodedPassword := url.QueryEscape(cfg.Database.Password)
-	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
-		cfg.Database.User,
-		encodedPassword,
-		hostPort,
-		cfg.Database.Name,
-		cfg.Database.SSLMode,
-	)
-
-	conn, err := pgx.Connect(ctx, dsn)
+	conn, err := Connect(ctx, logger, cfg.Database)
 	if err != nil {
 		return err
 	}
 	defer conn.Close(ctx)
 
-	baseTree, err := fs.Sub(migrations, "migrations")
-	if err != nil {
-		return fmt.Errorf("retrieving database migrations subtree: %w", err)
-	}
-
-	projectDirs, err := discoverProjectDirs(baseTree)
+	projects, err := MigrationProjects()
 	if err != nil {
 		return err
 	}
 
-	if len(projectDirs) == 0 {
-		if err := migrateSubtree(ctx, logger, conn, baseTree, ".", "schema_version"); err != nil {
+	for _, project := range projects {
+		m, err := NewMigrator(conn, project)
+		if err != nil {
 			return err
 		}
-		return nil
-	}
+		if m.Latest() == 0 {
+			continue
+		}
 
-	logger.Info().Strs("projects", projectDirs).Msg("discovered migration project folders")
-	for _, project := range projectDirs {
-		schemaTable := fmt.Sprintf("schema_version_%s", strings.ToLower(project))
-		logger.Info().Str("project", project).Str("schema_table", schemaTable).Msg("running migrations")
-		if err := migrateSubtree(ctx, logger, conn, baseTree, project, schemaTable); err != nil {
+		logger.Info().Str("project", project.Name).Str("schema_table", project.SchemaTable).Msg("running migrations")
+		from, err := m.MigrateTo(ctx, logger, m.Latest())
+		if err != nil {
 			return err
 		}
+		if from == m.Latest() {
+			logger.Info().Msgf("database schema up to date for %s, version %d", project.SchemaTable, m.Latest())
+		} else {
+			logger.Info().Msgf("migrated database schema for %s, from %d to %d", project.SchemaTable, from, m.Latest())
+		}
 	}
 
 	return nil
 }
 
+// Connect opens a single connection to the configured database, retrying
+// while it comes up. Migrations need one session for their whole run.
+func Connect(ctx context.Context, logger *zerolog.Logger, cfg config.DatabaseConfig) (*pgx.Conn, error) {
+	var conn *pgx.Conn
+	err := retryConnect(ctx, logger, cfg, func(ctx context.Context) (err error) {
+		conn, err = pgx.Connect(ctx, DSN(cfg))
+		return err
+	})
+	return conn, err
+}
+
+// MigrationProjects lists the embedded migration projects by name. Without
+// project folders, the migrations/ root is the single unnamed project.
+func MigrationProjects() ([]MigrationProject, error) {
+	baseTree, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("retrieving database migrations subtree: %w", err)
+	}
+
+	projectDirs, err := discoverProjectDirs(baseTree)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectDirs) == 0 {
+		return []MigrationProject{{SchemaTable: versionTableSchema + ".schema_version", dir: "."}}, nil
+	}
+
+	// The shared schema goes first, module migrations depend on it.
+	sort.Slice(projectDirs, func(i, j int) bool {
+		if (projectDirs[i] == SharedSchema) != (projectDirs[j] == SharedSchema) {
+			return projectDirs[i] == SharedSchema
+		}
+		return projectDirs[i] < projectDirs[j]
+	})
+	projects := make([]MigrationProject, 0, len(projectDirs))
+	for _, dir := range projectDirs {
+		projects = append(projects, MigrationProject{
+			Name:        dir,
+			SchemaTable: fmt.Sprintf("%s.schema_version_%s", versionTableSchema, strings.ToLower(dir)),
+			dir:         dir,
+		})
+	}
+	return projects, nil
+}
+
 func discoverProjectDirs(baseTree fs.FS) ([]string, error) {
 	// Prefer directory listing when available.
 	entries, err := fs.ReadDir(baseTree, ".")
@@ -115,46 +194,247 @@ func discoverProjectDirs(baseTree fs.FS) ([]string, error) {
 	return dirs, nil
 }
 
-func migrateSubtree(
-	ctx context.Context,
-	logger *zerolog.Logger,
-	conn *pgx.Conn,
-	baseTree fs.FS,
-	dir string,
-	schemaTable string,
-) error {
-	var subtree fs.FS
-	var err error
-	if dir == "." {
-		subtree = baseTree
-	} else {
-		subtree, err = fs.Sub(baseTree, dir)
+// NewMigrator loads the migrations of project. Loading does not touch the
+// database; conn is used once the migrator reads or changes the version.
+func NewMigrator(conn *pgx.Conn, project MigrationProject) (*Migrator, error) {
+	subtree, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("retrieving database migrations subtree: %w", err)
+	}
+	if project.dir != "." {
+		subtree, err = fs.Sub(subtree, project.dir)
 		if err != nil {
-			return fmt.Errorf("retrieving database migrations subtree %q: %w", dir, err)
+			return nil, fmt.Errorf("retrieving database migrations subtree %q: %w", project.dir, err)
 		}
 	}
 
-	m, err := tern.NewMigrator(ctx, conn, schemaTable)
+	// A migrator without a conn only parses, it does not create the version
+	// table.
+	loader, err := tern.NewMigrator(context.Background(), nil, project.SchemaTable)
 	if err != nil {
-		return fmt.Errorf("constructing database migrator for %q: %w", dir, err)
+		return nil, err
 	}
-	if err := m.LoadMigrations(subtree); err != nil {
-		return fmt.Errorf("loading database migrations for %q: %w", dir, err)
+	if err := loader.LoadMigrations(subtree); err != nil {
+		return nil, fmt.Errorf("loading database migrations for %q: %w", project.dir, err)
 	}
-	if len(m.Migrations) == 0 {
-		return nil
+
+	return &Migrator{
+		Project:    project,
+		conn:       conn,
+		migrations: loader.Migrations,
+	}, nil
+}
+
+// Latest is the version after the last embedded migration.
+func (m *Migrator) Latest() int32 {
+	return int32(len(m.migrations))
+}
+
+// CurrentVersion reads the applied version, 0 when the project has never
+// been migrated. It does not create the version table.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int32, error) {
+	table, err := m.versionTable(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if table == "" {
+		return 0, nil
+	}
+
+	var version int32
+	if err := m.conn.QueryRow(ctx, "SELECT version FROM "+table).Scan(&version); err != nil {
+		return 0, fmt.Errorf("retrieving current database migration version for %q: %w", m.Project.Name, err)
+	}
+	return version, nil
+}
+
+// versionTable finds the version table of the project. Before version tables
+// were schema-qualified, a search_path left behind by the previous project's
+// migrations could create one in that project's schema instead; it is found
+// there too.
+func (m *Migrator) versionTable(ctx context.Context) (string, error) {
+	_, name, _ := strings.Cut(m.Project.SchemaTable, ".")
+
+	var table string
+	err := m.conn.QueryRow(ctx, `
+		SELECT format('%I.%I', schemaname, tablename)
+		FROM pg_catalog.pg_tables
+		WHERE tablename = $1
+		ORDER BY schemaname = $2 DESC, schemaname
+		LIMIT 1`, name, versionTableSchema).Scan(&table)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
 	}
-	from, err := m.GetCurrentVersion(ctx)
 	if err != nil {
-		return fmt.Errorf("retreiving current database migration version for %q", dir)
+		return "", fmt.Errorf("looking up %s: %w", m.Project.SchemaTable, err)
 	}
-	if err := m.Migrate(ctx); err != nil {
+	return table, nil
+}
+
+// relocateVersionTable moves a version table found outside
+// versionTableSchema back into it.
+func (m *Migrator) relocateVersionTable(ctx context.Context, logger *zerolog.Logger) error {
+	table, err := m.versionTable(ctx)
+	if err != nil || table == "" || table == m.Project.SchemaTable {
 		return err
 	}
-	if from == int32(len(m.Migrations)) {
-		logger.Info().Msgf("database schema up to date for %s, version %d", schemaTable, len(m.Migrations))
-	} else {
-		logger.Info().Msgf("migrated database schema for %s, from %d to %d", schemaTable, from, len(m.Migrations))
+
+	if _, err := m.conn.Exec(ctx, "ALTER TABLE "+table+" SET SCHEMA "+versionTableSchema); err != nil {
+		return fmt.Errorf("moving %s to %s: %w", table, m.Project.SchemaTable, err)
+	}
+	logger.Warn().
+		Str("project", m.Project.Name).
+		Str("from", table).
+		Str("to", m.Project.SchemaTable).
+		Msg("moved migration version table")
+	return nil
+}
+
+func (m *Migrator) Status(ctx context.Context) (MigrationStatus, error) {
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	status := MigrationStatus{
+		MigrationProject: m.Project,
+		Current:          current,
+		Latest:           m.Latest(),
+		Pending:          []string{},
+		Drifted:          []string{},
+	}
+	for _, migration := range m.migrations[min(max(current, 0), m.Latest()):] {
+		status.Pending = append(status.Pending, migration.Name)
+	}
+
+	drifts, _, err := m.Drifts(ctx, current)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	for _, drift := range drifts {
+		status.Drifted = append(status.Drifted, drift.Name)
+	}
+	return status, nil
+}
+
+// Plan returns the steps that would move the schema from its current
+// version to target, in order, without running them.
+func (m *Migrator) Plan(ctx context.Context, target int32) ([]MigrationStep, error) {
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkVersion(target); err != nil {
+		return nil, err
+	}
+	if err := m.checkVersion(current); err != nil {
+		return nil, fmt.Errorf("current %w", err)
+	}
+
+	var steps []MigrationStep
+	for v := current; v < target; v++ {
+		migration := m.migrations[v]
+		steps = append(steps, m.step(migration, DirectionUp, migration.UpSQL))
+	}
+	for v := current; v > target; v-- {
+		migration := m.migrations[v-1]
+		if migration.DownSQL == "" {
+			return nil, fmt.Errorf("%s: migration %s has no down section", m.Project.Name, migration.Name)
+		}
+		steps = append(steps, m.step(migration, DirectionDown, migration.DownSQL))
+	}
+	return steps, nil
+}
+
+// MigrateTo applies or rolls back migrations until the schema is at target
+// and returns the version it started from. Each migration runs in its own
+// transaction, which also records or removes its checksum. The run holds
+// the project's advisory lock, and refuses to start with a DriftError if an
+// applied migration was edited since.
+func (m *Migrator) MigrateTo(ctx context.Context, logger *zerolog.Logger, target int32) (from int32, err error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	// Start from the session defaults, not the search_path the previous
+	// project's migrations left behind.
+	if _, err := m.conn.Exec(ctx, "RESET search_path"); err != nil {
+		return 0, err
+	}
+	if err := m.relocateVersionTable(ctx, logger); err != nil {
+		return 0, err
+	}
+	if err := m.ensureChecksumTable(ctx); err != nil {
+		return 0, fmt.Errorf("creating %s: %w", ChecksumTable, err)
+	}
+
+	// Read under the lock: another process may have just migrated.
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(ctx, logger, current); err != nil {
+		return current, err
+	}
+
+	// Fail before changing anything if a rollback would hit an irreversible
+	// migration half way.
+	if _, err := m.Plan(ctx, target); err != nil {
+		return current, err
+	}
+
+	migrator, err := tern.NewMigrator(ctx, m.conn, m.Project.SchemaTable)
+	if err != nil {
+		return current, fmt.Errorf("constructing database migrator for %q: %w", m.Project.dir, err)
+	}
+	migrator.Migrations = m.migrations
+	// tern calls OnStart on m.conn inside the migration's transaction, so the
+	// checksum commits or rolls back together with the migration.
+	var checksumErr error
+	migrator.OnStart = func(sequence int32, name, direction, _ string) {
+		logger.Info().
+			Str("project", m.Project.Name).
+			Int32("version", sequence).
+			Str("migration", name).
+			Str("direction", direction).
+			Msg("running migration")
+
+		if checksumErr != nil {
+			return
+		}
+		if direction == DirectionUp {
+			checksumErr = m.recordChecksum(ctx, m.migrations[sequence-1])
+		} else {
+			checksumErr = m.deleteChecksum(ctx, sequence)
+		}
+	}
+
+	if err := migrator.MigrateTo(ctx, target); err != nil {
+		// A failed checksum write aborts the transaction, so the migration
+		// fails right after it; report the cause.
+		if checksumErr != nil {
+			return current, checksumErr
+		}
+		return current, err
+	}
+	return current, nil
+}
+
+func (m *Migrator) checkVersion(version int32) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("version %d of %s is outside the valid versions of 0 to %d", version, m.Project.Name, m.Latest())
 	}
 	return nil
 }
+
+func (m *Migrator) step(migration *tern.Migration, direction, sql string) MigrationStep {
+	return MigrationStep{
+		Project:   m.Project.Name,
+		Version:   migration.Sequence,
+		Name:      migration.Name,
+		Direction: direction,
+		SQL:       sql,
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import "testing"
+
+// TestShippedMigrationsUnchanged pins the up SQL of migrations that were
+// released before down sections existed. Adding a down section must leave
+// what runs, and so its checksum, exactly as deployed databases applied it.
+func TestShippedMigrationsUnchanged(t *testing.T) {
+	want := map[string]map[string]string{
+		"task": {
+			"001_setup.sql": "5875f40c9a41b9a8c1d79e010ff28d54f387d8de01c1dbf294ec628449599bcd",
+			"002_task.sql":  "754e8ac802970223ee644c65e7833b63cb3671afb0cc41608e328e7c3ecb9325",
+		},
+		"todo": {
+			"001_setup.sql": "1ef246f5ae2af521369d5a46e018fc6fdc70454215febba0e6af2d3f89ed6d5e",
+			"002_todo.sql":  "070af88b1b860d6de5865e2c90345a3678111a3cad76f1cfeb9b31128fa91665",
+		},
+	}
+
+	projects, err := MigrationProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, project := range projects {
+		sums, ok := want[project.Name]
+		if !ok {
+			continue
+		}
+		m, err := NewMigrator(nil, project)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, migration := range m.migrations {
+			sum, ok := sums[migration.Name]
+			if !ok {
+				continue
+			}
+			delete(sums, migration.Name)
+
+			if got := checksum(migration.UpSQL); got != sum {
+				t.Errorf("%s/%s up SQL changed: checksum %s, want %s", project.Name, migration.Name, got, sum)
+			}
+			if migration.DownSQL == "" {
+				t.Errorf("%s/%s has no down section", project.Name, migration.Name)
+			}
+		}
+	}
+
+	for project, sums := range want {
+		for name := range sums {
+			t.Errorf("%s/%s not found", project, name)
+		}
+	}
+}
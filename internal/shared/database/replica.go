@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier runs statements. It is implemented by the pools and by pgx.Tx, so
+// repositories work the same inside and outside a transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type (
+	txKey      struct{}
+	sessionKey struct{}
+)
+
+// session tracks whether a request has written to the primary.
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession starts a request scope on ctx. Once Writer has been used with
+// it, Reader stays on the primary for the rest of the request so the request
+// reads its own writes despite replication lag.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// WithTx carries an open transaction on ctx. Reader and Writer return it, so
+// every statement using ctx runs inside the transaction.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried on ctx, if any.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Reader returns where to send a read: the transaction on ctx, the primary if
+// the request already wrote, otherwise the replica when there is one.
+func (db *Database) Reader(ctx context.Context) Querier {
+	return db.scope.wrap(db.reader(ctx))
+}
+
+func (db *Database) reader(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	if db.Replica == nil {
+		return db.Pool
+	}
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok && s.wrote.Load() {
+		return db.Pool
+	}
+	return db.Replica
+}
+
+// Writer returns the transaction on ctx or the primary, and pins the rest of
+// the request's reads to the primary.
+func (db *Database) Writer(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return db.scope.wrap(tx)
+	}
+	markWritten(ctx)
+	return db.scope.wrap(db.Pool)
+}
+
+func markWritten(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// idlePool returns a pool that is never connected: pgxpool only dials on
+// first use, so it stands in for the primary or replica in routing tests.
+func idlePool(t *testing.T, host string) *pgxpool.Pool {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), "postgres://"+host+"/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestReaderAndWriter(t *testing.T) {
+	primary := idlePool(t, "primary.invalid")
+	replica := idlePool(t, "replica.invalid")
+
+	tests := []struct {
+		name    string
+		replica *pgxpool.Pool
+		session bool
+		write   bool
+		want    *pgxpool.Pool
+	}{
+		{"no replica", nil, true, false, primary},
+		{"no replica after a write", nil, true, true, primary},
+		{"replica", replica, true, false, replica},
+		{"read your writes", replica, true, true, primary},
+		{"write without a session", replica, false, true, replica},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &Database{Pool: primary, Replica: tt.replica}
+			ctx := context.Background()
+			if tt.session {
+				ctx = WithSession(ctx)
+			}
+
+			if tt.write {
+				if got := db.Writer(ctx); got != Querier(primary) {
+					t.Fatalf("Writer() = %v, want the primary", got)
+				}
+			}
+			if got := db.Reader(ctx); got != Querier(tt.want) {
+				t.Errorf("Reader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterPinsOnlyItsOwnRequest(t *testing.T) {
+	primary := idlePool(t, "primary.invalid")
+	replica := idlePool(t, "replica.invalid")
+	db := &Database{Pool: primary, Replica: replica}
+
+	wrote := WithSession(context.Background())
+	other := WithSession(context.Background())
+	db.Writer(wrote)
+
+	if got := db.Reader(wrote); got != Querier(primary) {
+		t.Errorf("Reader() after a write = %v, want the primary", got)
+	}
+	if got := db.Reader(other); got != Querier(replica) {
+		t.Errorf("Reader() of another request = %v, want the replica", got)
+	}
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SharedSchema holds the helpers every module schema uses, such as camel and
+// trigger_set_updated_at. It is migrated first and is on every module's
+// search_path.
+const SharedSchema = "shared"
+
+// tableReference matches a schema-qualified table after the keywords that
+// introduce one, e.g. "FROM todo.todos" or "UPDATE todo.todos".
+var tableReference = regexp.MustCompile(`(?i)\b(?:from|join|into|update|table|only)\s+"?([a-z_][a-z0-9_$]*)"?\s*\.`)
+
+// CrossSchemaError is returned for a statement of a module that names a table
+// in another module's schema without declaring it.
+type CrossSchemaError struct {
+	Schema string
+	Target string
+}
+
+func (e *CrossSchemaError) Error() string {
+	return fmt.Sprintf("module schema %q may not access schema %q; declare it in the module's UsesSchemas", e.Schema, e.Target)
+}
+
+// schemaScope confines a module database to its schema: statements run with
+// its search_path and may not name the tables of module schemas the module
+// did not declare.
+type schemaScope struct {
+	schema     string
+	searchPath string
+	forbidden  []string
+}
+
+// ForSchema returns a Database for the module owning schema. It shares the
+// pools of db, and its statements run with search_path set to schema, then
+// the uses schemas, then SharedSchema, so unqualified tables resolve in the
+// module's own schema whatever the session defaults. Statements run through
+// Reader, Writer and InTx that name a table of another module's schema
+// outside uses fail with a CrossSchemaError.
+func (db *Database) ForSchema(schema string, uses ...string) (*Database, error) {
+	projects, err := MigrationProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := append([]string{schema}, uses...)
+	allowed = append(allowed, SharedSchema)
+
+	searchPath := make([]string, len(allowed))
+	for i, name := range allowed {
+		searchPath[i] = pgx.Identifier{name}.Sanitize()
+	}
+
+	scope := &schemaScope{schema: schema, searchPath: strings.Join(searchPath, ", ")}
+	for _, project := range projects {
+		if project.Name != "" && !slices.Contains(allowed, project.Name) {
+			scope.forbidden = append(scope.forbidden, project.Name)
+		}
+	}
+
+	db.log.Info().
+		Str("schema", schema).
+		Str("search_path", scope.searchPath).
+		Msg("scoped module database")
+
+	return &Database{
+		Pool:    db.Pool,
+		Replica: db.Replica,
+		Tracer:  db.Tracer,
+		log:     db.log,
+		scope:   scope,
+		beginTx: db.beginTx,
+	}, nil
+}
+
+// Schema is the module schema the database is scoped to, empty for the
+// unscoped primary.
+func (db *Database) Schema() string {
+	if db.scope == nil {
+		return ""
+	}
+	return db.scope.schema
+}
+
+type searchPathKey struct{}
+
+// searchPathData is the CustomData key holding the search_path a pooled
+// connection was last set to.
+const searchPathData = "search_path"
+
+// context carries the scope's search_path to the pool, for the connection
+// acquired with it.
+func (s *schemaScope) context(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, searchPathKey{}, s.searchPath)
+}
+
+// setSearchPath is the BeforeAcquire hook of the pools. It gives the
+// connection the search_path of the module database acquiring it, or the
+// session default for the unscoped one. Connections remember their
+// search_path, so it only runs when a connection changes modules. A
+// connection it cannot set up is discarded.
+func setSearchPath(ctx context.Context, conn *pgx.Conn) bool {
+	want, _ := ctx.Value(searchPathKey{}).(string)
+
+	data := conn.PgConn().CustomData()
+	if current, _ := data[searchPathData].(string); current == want {
+		return true
+	}
+
+	sql := "RESET search_path"
+	if want != "" {
+		sql = "SET search_path TO " + want
+	}
+	// On the PgConn, so the query tracer does not count it.
+	if err := conn.PgConn().Exec(ctx, sql).Close(); err != nil {
+		return false
+	}
+	data[searchPathData] = want
+	return true
+}
+
+func (s *schemaScope) check(sql string) error {
+	if len(s.forbidden) == 0 {
+		return nil
+	}
+
+	sql = stringLiteral.ReplaceAllString(sql, "''")
+	for _, match := range tableReference.FindAllStringSubmatch(sql, -1) {
+		target := strings.ToLower(match[1])
+		if slices.Contains(s.forbidden, target) {
+			return &CrossSchemaError{Schema: s.schema, Target: target}
+		}
+	}
+	return nil
+}
+
+// wrap returns q running every statement in the scope.
+func (s *schemaScope) wrap(q Querier) Querier {
+	if s == nil {
+		return q
+	}
+	return scopedQuerier{Querier: q, scope: s}
+}
+
+type scopedQuerier struct {
+	Querier
+	scope *schemaScope
+}
+
+func (q scopedQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := q.scope.check(sql); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return q.Querier.Exec(q.scope.context(ctx), sql, args...)
+}
+
+func (q scopedQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := q.scope.check(sql); err != nil {
+		return nil, err
+	}
+	return q.Querier.Query(q.scope.context(ctx), sql, args...)
+}
+
+func (q scopedQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := q.scope.check(sql); err != nil {
+		return errRow{err: err}
+	}
+	return q.Querier.QueryRow(q.scope.context(ctx), sql, args...)
+}
+
+// errRow is a pgx.Row that fails with err when scanned.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
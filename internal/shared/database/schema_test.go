@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// recordingQuerier remembers the search_path carried on the ctx of the last
+// statement, as the pool's BeforeAcquire hook would see it.
+type recordingQuerier struct {
+	searchPath *string
+}
+
+func (q recordingQuerier) record(ctx context.Context) {
+	path, _ := ctx.Value(searchPathKey{}).(string)
+	*q.searchPath = path
+}
+
+func (q recordingQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	q.record(ctx)
+	return pgconn.CommandTag{}, nil
+}
+
+func (q recordingQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.record(ctx)
+	return nil, nil
+}
+
+func (q recordingQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.record(ctx)
+	return nil
+}
+
+func TestSchemaScope(t *testing.T) {
+	logger := zerolog.Nop()
+	root := &Database{log: &logger}
+
+	db, err := root.ForSchema("task", "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Schema() != "task" || root.Schema() != "" {
+		t.Fatalf("Schema() = %q, root %q", db.Schema(), root.Schema())
+	}
+
+	// A module schema the task module did not declare
+	db.scope.forbidden = append(db.scope.forbidden, "billing")
+
+	const taskPath = `"task", "todo", "shared"`
+	tests := []struct {
+		name           string
+		db             *Database
+		sql            string
+		wantSearchPath string
+		wantErr        bool
+	}{
+		{"own schema", db, "SELECT * FROM tasks", taskPath, false},
+		{"declared schema", db, "SELECT * FROM todo.todos", taskPath, false},
+		{"undeclared schema", db, `SELECT * FROM "billing".invoices`, "", true},
+		{"undeclared schema in a string", db, "SELECT 'FROM billing.invoices'", taskPath, false},
+		{"unscoped database", root, "SELECT * FROM billing.invoices", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchPath := "unset"
+			q := tt.db.scope.wrap(recordingQuerier{searchPath: &searchPath})
+
+			_, err := q.Exec(context.Background(), tt.sql)
+			if tt.wantErr {
+				var cerr *CrossSchemaError
+				if !errors.As(err, &cerr) || cerr.Schema != "task" || cerr.Target != "billing" {
+					t.Fatalf("err = %v, want CrossSchemaError for billing", err)
+				}
+				if searchPath != "unset" {
+					t.Errorf("rejected statement reached the pool")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec: %v", err)
+			}
+			if searchPath != tt.wantSearchPath {
+				t.Errorf("search_path = %q, want %q", searchPath, tt.wantSearchPath)
+			}
+		})
+	}
+}
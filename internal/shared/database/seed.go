@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"go.yaml.in/yaml/v3"
+)
+
+// Seed files live in seeds/<project>/<environment>/ and run in name order
+// with search_path set to the project's schema. SQL files run as is; YAML
+// files are Fixtures. Both must be safe to run again.
+//
+//go:embed seeds
+var seeds embed.FS
+
+// SeedFile is one seed file of a project for an environment.
+type SeedFile struct {
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Name        string `json:"name"`
+	path        string
+}
+
+// Fixture is a YAML seed file: rows inserted into one table, skipping rows
+// whose Key columns already match, or updating them when Update is set.
+//
+//	table: tasks
+//	key: [id]
+//	rows:
+//	  - id: 8f1e0c4e-3c1b-4a55-9f57-2f0d8a0b6b01
+//	    title: Write the README
+type Fixture struct {
+	Table  string           `yaml:"table"`
+	Key    []string         `yaml:"key"`
+	Update bool             `yaml:"update"`
+	Rows   []map[string]any `yaml:"rows"`
+}
+
+// Beginner starts transactions; *pgx.Conn and *pgxpool.Pool implement it.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// SeedFiles lists the seed files of env for the given projects, or for every
+// project when none is given, in the order Seed runs them.
+func SeedFiles(env string, projects ...string) ([]SeedFile, error) {
+	all, err := MigrationProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []SeedFile
+	for _, project := range all {
+		if len(projects) > 0 && !slices.Contains(projects, project.Name) {
+			continue
+		}
+
+		dir := path.Join("seeds", project.dir, env)
+		entries, err := fs.ReadDir(seeds, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", dir, err)
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if !entry.IsDir() && isSeedFile(entry.Name()) {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			files = append(files, SeedFile{
+				Project:     project.Name,
+				Environment: env,
+				Name:        name,
+				path:        path.Join(dir, name),
+			})
+		}
+	}
+	return files, nil
+}
+
+// Seed runs the seed files of env for the given projects, or for every
+// project when none is given. Each file runs in its own transaction; the
+// first failure stops the run.
+func Seed(ctx context.Context, logger *zerolog.Logger, db Beginner, env string, projects ...string) error {
+	files, err := SeedFiles(env, projects...)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		logger.Info().Str("environment", env).Msg("no seed files")
+		return nil
+	}
+
+	for _, file := range files {
+		if err := seedFile(ctx, db, file); err != nil {
+			return fmt.Errorf("seeding %s/%s/%s: %w", file.Project, file.Environment, file.Name, err)
+		}
+		logger.Info().
+			Str("project", file.Project).
+			Str("environment", env).
+			Str("file", file.Name).
+			Msg("applied seed file")
+	}
+	return nil
+}
+
+func isSeedFile(name string) bool {
+	switch path.Ext(name) {
+	case ".sql", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func seedFile(ctx context.Context, db Beginner, file SeedFile) error {
+	content, err := fs.ReadFile(seeds, file.path)
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if file.Project != "" {
+			searchPath := pgx.Identifier{file.Project}.Sanitize() + ", " + pgx.Identifier{SharedSchema}.Sanitize()
+			if _, err := tx.Exec(ctx, "SET LOCAL search_path TO "+searchPath); err != nil {
+				return err
+			}
+		}
+
+		if path.Ext(file.Name) == ".sql" {
+			_, err := tx.Exec(ctx, string(content))
+			return err
+		}
+
+		var fixture Fixture
+		if err := yaml.Unmarshal(content, &fixture); err != nil {
+			return fmt.Errorf("parsing fixture: %w", err)
+		}
+		return fixture.insert(ctx, tx)
+	})
+}
+
+// insert writes the fixture rows one statement per row, since rows may set
+// different columns.
+func (f Fixture) insert(ctx context.Context, tx pgx.Tx) error {
+	if f.Table == "" {
+		return errors.New("fixture has no table")
+	}
+	table := pgx.Identifier{f.Table}.Sanitize()
+
+	for i, row := range f.Rows {
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		names := make([]string, len(columns))
+		params := make([]string, len(columns))
+		args := make([]any, len(columns))
+		for j, column := range columns {
+			names[j] = pgx.Identifier{column}.Sanitize()
+			params[j] = fmt.Sprintf("$%d", j+1)
+			args[j] = row[column]
+		}
+
+		stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+			table, strings.Join(names, ", "), strings.Join(params, ", "), f.onConflict(columns))
+		if _, err := tx.Exec(ctx, stmt, args...); err != nil {
+			return fmt.Errorf("row %d of %s: %w", i+1, f.Table, err)
+		}
+	}
+	return nil
+}
+
+func (f Fixture) onConflict(columns []string) string {
+	if len(f.Key) == 0 {
+		return "ON CONFLICT DO NOTHING"
+	}
+
+	key := make([]string, len(f.Key))
+	for i, column := range f.Key {
+		key[i] = pgx.Identifier{column}.Sanitize()
+	}
+	target := "ON CONFLICT (" + strings.Join(key, ", ") + ")"
+
+	var set []string
+	for _, column := range columns {
+		if f.Update && !slices.Contains(f.Key, column) {
+			name := pgx.Identifier{column}.Sanitize()
+			set = append(set, name+" = EXCLUDED."+name)
+		}
+	}
+	if len(set) == 0 {
+		return target + " DO NOTHING"
+	}
+	return target + " DO UPDATE SET " + strings.Join(set, ", ")
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"path"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestFixtureOnConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture Fixture
+		columns []string
+		want    string
+	}{
+		{
+			name:    "no key skips any conflict",
+			fixture: Fixture{},
+			columns: []string{"id", "title"},
+			want:    "ON CONFLICT DO NOTHING",
+		},
+		{
+			name:    "no key ignores update",
+			fixture: Fixture{Update: true},
+			columns: []string{"id", "title"},
+			want:    "ON CONFLICT DO NOTHING",
+		},
+		{
+			name:    "key skips existing rows",
+			fixture: Fixture{Key: []string{"id"}},
+			columns: []string{"id", "title"},
+			want:    `ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:    "update sets the other columns",
+			fixture: Fixture{Key: []string{"id"}, Update: true},
+			columns: []string{"description", "id", "title"},
+			want:    `ON CONFLICT ("id") DO UPDATE SET "description" = EXCLUDED."description", "title" = EXCLUDED."title"`,
+		},
+		{
+			name:    "composite key",
+			fixture: Fixture{Key: []string{"tenant", "slug"}, Update: true},
+			columns: []string{"name", "slug", "tenant"},
+			want:    `ON CONFLICT ("tenant", "slug") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			name:    "update with only key columns",
+			fixture: Fixture{Key: []string{"id"}, Update: true},
+			columns: []string{"id"},
+			want:    `ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:    "identifiers are quoted",
+			fixture: Fixture{Key: []string{`we"ird`}, Update: true},
+			columns: []string{`we"ird`, "order"},
+			want:    `ON CONFLICT ("we""ird") DO UPDATE SET "order" = EXCLUDED."order"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fixture.onConflict(tt.columns); got != tt.want {
+				t.Errorf("onConflict = %s\nwant          %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSeedFixturesParse keeps every embedded fixture loadable.
+func TestSeedFixturesParse(t *testing.T) {
+	for _, env := range []string{"local", "demo", "test"} {
+		files, err := SeedFiles(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Errorf("no %s seeds", env)
+		}
+
+		for _, file := range files {
+			content, err := seeds.ReadFile(file.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path.Ext(file.Name) == ".sql" {
+				continue
+			}
+
+			var fixture Fixture
+			if err := yaml.Unmarshal(content, &fixture); err != nil {
+				t.Errorf("%s: %v", file.path, err)
+				continue
+			}
+			if fixture.Table == "" || len(fixture.Key) == 0 || len(fixture.Rows) == 0 {
+				t.Errorf("%s: table %q, key %v, %d rows", file.path, fixture.Table, fixture.Key, len(fixture.Rows))
+			}
+		}
+	}
+}
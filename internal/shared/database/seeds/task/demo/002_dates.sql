@@ -0,0 +1,19 @@
+-- Dates relative to the day the seeds run, so the demo always shows tasks
+-- that are overdue, due this week and due later, finished tasks, and one
+-- task in the trash. Like the fixture, reruns reset them.
+UPDATE tasks SET due_date = CASE id
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d001' THEN CURRENT_TIMESTAMP + INTERVAL '2 days'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d002' THEN CURRENT_TIMESTAMP - INTERVAL '1 day'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d003' THEN CURRENT_TIMESTAMP + INTERVAL '5 days'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d004' THEN CURRENT_TIMESTAMP + INTERVAL '14 days'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d006' THEN CURRENT_TIMESTAMP - INTERVAL '6 days'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d007' THEN CURRENT_TIMESTAMP - INTERVAL '3 days'
+    END,
+    completed_at = CASE id
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d006' THEN CURRENT_TIMESTAMP - INTERVAL '7 days'
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d007' THEN CURRENT_TIMESTAMP - INTERVAL '2 days'
+    END,
+    deleted_at = CASE id
+        WHEN '5d1e7b3c-8a2f-4e61-b7d4-00000000d009' THEN CURRENT_TIMESTAMP - INTERVAL '1 day'
+    END
+WHERE id::text LIKE '5d1e7b3c-8a2f-4e61-b7d4-00000000d%';
@@ -0,0 +1,11 @@
+-- Due dates relative to the day the seeds run: one overdue, one due soon.
+-- Only rows without a due date are touched, so reruns keep earlier dates.
+UPDATE tasks SET due_date = CURRENT_TIMESTAMP - INTERVAL '2 days'
+WHERE id = '7a5c0e2e-4b1d-4c36-9a0f-000000000002' AND due_date IS NULL;
+
+UPDATE tasks SET due_date = CURRENT_TIMESTAMP + INTERVAL '3 days'
+WHERE id = '7a5c0e2e-4b1d-4c36-9a0f-000000000001' AND due_date IS NULL;
+
+UPDATE tasks SET completed_at = updated_at
+WHERE status = 'completed' AND completed_at IS NULL
+  AND id IN ('7a5c0e2e-4b1d-4c36-9a0f-000000000001', '7a5c0e2e-4b1d-4c36-9a0f-000000000002', '7a5c0e2e-4b1d-4c36-9a0f-000000000003', '7a5c0e2e-4b1d-4c36-9a0f-000000000004', '7a5c0e2e-4b1d-4c36-9a0f-000000000005');
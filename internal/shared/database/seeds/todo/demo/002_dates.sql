@@ -0,0 +1,18 @@
+-- Dates relative to the day the seeds run, so the demo always shows todos
+-- that are overdue, due this week and due later, finished todos, and one
+-- todo in the trash. Like the fixture, reruns reset them.
+UPDATE todos SET due_date = CASE id
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d001' THEN CURRENT_TIMESTAMP + INTERVAL '3 days'
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d002' THEN CURRENT_TIMESTAMP - INTERVAL '2 days'
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d003' THEN CURRENT_TIMESTAMP + INTERVAL '1 day'
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d004' THEN CURRENT_TIMESTAMP + INTERVAL '10 days'
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d005' THEN CURRENT_TIMESTAMP - INTERVAL '1 day'
+    END,
+    completed_at = CASE id
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d005' THEN CURRENT_TIMESTAMP - INTERVAL '1 day'
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d006' THEN CURRENT_TIMESTAMP - INTERVAL '4 days'
+    END,
+    deleted_at = CASE id
+        WHEN '9c4a2f7e-1b6d-4d83-a5e9-00000000d008' THEN CURRENT_TIMESTAMP - INTERVAL '2 days'
+    END
+WHERE id::text LIKE '9c4a2f7e-1b6d-4d83-a5e9-00000000d%';
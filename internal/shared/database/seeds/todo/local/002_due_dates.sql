@@ -0,0 +1,11 @@
+-- Due dates relative to the day the seeds run: one overdue, one due soon.
+-- Only rows without a due date are touched, so reruns keep earlier dates.
+UPDATE todos SET due_date = CURRENT_TIMESTAMP - INTERVAL '2 days'
+WHERE id = '3f9d6b1a-2e7c-4f58-8b3d-000000000002' AND due_date IS NULL;
+
+UPDATE todos SET due_date = CURRENT_TIMESTAMP + INTERVAL '3 days'
+WHERE id = '3f9d6b1a-2e7c-4f58-8b3d-000000000001' AND due_date IS NULL;
+
+UPDATE todos SET completed_at = updated_at
+WHERE status = 'completed' AND completed_at IS NULL
+  AND id IN ('3f9d6b1a-2e7c-4f58-8b3d-000000000001', '3f9d6b1a-2e7c-4f58-8b3d-000000000002', '3f9d6b1a-2e7c-4f58-8b3d-000000000003', '3f9d6b1a-2e7c-4f58-8b3d-000000000004');
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolStats is a snapshot of the connection pool.
+type PoolStats struct {
+	MaxConns                int32         `json:"max_conns"`
+	TotalConns              int32         `json:"total_conns"`
+	AcquiredConns           int32         `json:"acquired_conns"`
+	IdleConns               int32         `json:"idle_conns"`
+	ConstructingConns       int32         `json:"constructing_conns"`
+	AcquireCount            int64         `json:"acquire_count"`
+	AcquireDuration         time.Duration `json:"acquire_duration_ns"`
+	EmptyAcquireCount       int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64         `json:"canceled_acquire_count"`
+	NewConnsCount           int64         `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64         `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64         `json:"max_idle_destroy_count"`
+}
+
+// SlowestQueries returns the n statements with the most total time.
+func (db *Database) SlowestQueries(n int) []QueryStat {
+	if db.Tracer == nil {
+		return nil
+	}
+	stats := db.Tracer.Stats()
+	return stats[:min(n, len(stats))]
+}
+
+func (db *Database) Stats() PoolStats {
+	return poolStats(db.Pool)
+}
+
+// ReplicaStats returns the replica pool statistics, if there is a replica.
+func (db *Database) ReplicaStats() (PoolStats, bool) {
+	if db.Replica == nil {
+		return PoolStats{}, false
+	}
+	return poolStats(db.Replica), true
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		MaxConns:                s.MaxConns(),
+		TotalConns:              s.TotalConns(),
+		AcquiredConns:           s.AcquiredConns(),
+		IdleConns:               s.IdleConns(),
+		ConstructingConns:       s.ConstructingConns(),
+		AcquireCount:            s.AcquireCount(),
+		AcquireDuration:         s.AcquireDuration(),
+		EmptyAcquireCount:       s.EmptyAcquireCount(),
+		CanceledAcquireCount:    s.CanceledAcquireCount(),
+		NewConnsCount:           s.NewConnsCount(),
+		MaxLifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     s.MaxIdleDestroyCount(),
+	}
+}
+
+// WritePrometheus writes the pool and per-query statistics in the Prometheus
+// text format.
+func (db *Database) WritePrometheus(w io.Writer) error {
+	if err := db.Stats().WritePrometheus(w); err != nil {
+		return err
+	}
+	if db.Tracer == nil {
+		return nil
+	}
+	return writeQueryMetrics(w, db.Tracer.Stats())
+}
+
+// WritePrometheus writes the pool statistics in the Prometheus text format.
+func (s PoolStats) WritePrometheus(w io.Writer) error {
+	metrics := []struct {
+		name, kind, help string
+		value            any
+	}{
+		{"db_pool_max_conns", "gauge", "Maximum size of the pool.", s.MaxConns},
+		{"db_pool_total_conns", "gauge", "Connections currently in the pool.", s.TotalConns},
+		{"db_pool_acquired_conns", "gauge", "Connections currently in use.", s.AcquiredConns},
+		{"db_pool_idle_conns", "gauge", "Idle connections.", s.IdleConns},
+		{"db_pool_constructing_conns", "gauge", "Connections being established.", s.ConstructingConns},
+		{"db_pool_acquire_total", "counter", "Successful acquires from the pool.", s.AcquireCount},
+		{"db_pool_acquire_duration_seconds_total", "counter", "Time spent acquiring connections.", s.AcquireDuration.Seconds()},
+		{"db_pool_empty_acquire_total", "counter", "Acquires that waited for a connection.", s.EmptyAcquireCount},
+		{"db_pool_canceled_acquire_total", "counter", "Acquires canceled by their context.", s.CanceledAcquireCount},
+		{"db_pool_new_conns_total", "counter", "Connections opened.", s.NewConnsCount},
+		{"db_pool_max_lifetime_destroy_total", "counter", "Connections closed for exceeding their lifetime.", s.MaxLifetimeDestroyCount},
+		{"db_pool_max_idle_destroy_total", "counter", "Connections closed for being idle too long.", s.MaxIdleDestroyCount},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", m.name, m.help, m.name, m.kind, m.name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeQueryMetrics(w io.Writer, stats []QueryStat) error {
+	metrics := []struct {
+		name, help string
+		value      func(QueryStat) any
+	}{
+		{"db_query_total", "Statements executed.", func(s QueryStat) any { return s.Count }},
+		{"db_query_errors_total", "Statements that failed.", func(s QueryStat) any { return s.Errors }},
+		{"db_query_slow_total", "Statements slower than the slow query threshold.", func(s QueryStat) any { return s.Slow }},
+		{"db_query_duration_seconds_total", "Time spent executing statements.", func(s QueryStat) any { return s.TotalDuration.Seconds() }},
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name); err != nil {
+			return err
+		}
+		for _, stat := range stats {
+			if _, err := fmt.Fprintf(w, "%s{query=\"%s\"} %v\n", m.name, labelEscaper.Replace(stat.Query), m.value(stat)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
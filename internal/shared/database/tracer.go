@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	loggerConfig "github.com/goku-m/main/internal/shared/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxTrackedQueries bounds the number of distinct statements timed;
+	// further statements are counted under otherQueries.
+	maxTrackedQueries = 500
+	otherQueries      = "other"
+	maxShapeLength    = 500
+)
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^\w$.])\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// QueryStat is the accumulated timing of one statement shape.
+type QueryStat struct {
+	Query         string        `json:"query"`
+	Count         int64         `json:"count"`
+	Errors        int64         `json:"errors"`
+	Slow          int64         `json:"slow"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	MaxDuration   time.Duration `json:"max_duration_ns"`
+}
+
+// QueryTracer times every statement, logs the ones slower than the threshold
+// and keeps per-statement statistics. It never logs argument values.
+type QueryTracer struct {
+	logger    *zerolog.Logger
+	threshold time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*QueryStat
+}
+
+type traceKey struct{}
+
+type traceData struct {
+	start time.Time
+	shape string
+	args  int
+}
+
+// NewQueryTracer creates a tracer logging statements slower than threshold.
+// A zero threshold disables slow query logging but keeps the statistics.
+func NewQueryTracer(logger *zerolog.Logger, threshold time.Duration) *QueryTracer {
+	return &QueryTracer{
+		logger:    logger,
+		threshold: threshold,
+		stats:     make(map[string]*QueryStat),
+	}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceData{
+		start: time.Now(),
+		shape: queryShape(data.SQL),
+		args:  len(data.Args),
+	})
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(traceKey{}).(traceData)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(trace.start)
+	slow := t.threshold > 0 && duration >= t.threshold
+	t.record(trace.shape, duration, data.Err != nil, slow)
+
+	if !slow {
+		return
+	}
+
+	event := t.logger.Warn().
+		Str("sql", trace.shape).
+		Int("args", trace.args).
+		Dur("duration", duration).
+		Dur("threshold", t.threshold)
+	if requestID := loggerConfig.RequestIDFromContext(ctx); requestID != "" {
+		event = event.Str("request_id", requestID)
+	}
+	if data.Err != nil {
+		event = event.Err(data.Err)
+	}
+	event.Msg("slow query")
+}
+
+func (t *QueryTracer) record(shape string, duration time.Duration, failed, slow bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stat, ok := t.stats[shape]
+	if !ok {
+		if len(t.stats) >= maxTrackedQueries {
+			shape = otherQueries
+			stat = t.stats[shape]
+		}
+		if stat == nil {
+			stat = &QueryStat{Query: shape}
+			t.stats[shape] = stat
+		}
+	}
+
+	stat.Count++
+	stat.TotalDuration += duration
+	stat.MaxDuration = max(stat.MaxDuration, duration)
+	if failed {
+		stat.Errors++
+	}
+	if slow {
+		stat.Slow++
+	}
+}
+
+// Stats returns the statistics of every statement, slowest in total first.
+func (t *QueryTracer) Stats() []QueryStat {
+	t.mu.Lock()
+	stats := make([]QueryStat, 0, len(t.stats))
+	for _, stat := range t.stats {
+		stats = append(stats, *stat)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].TotalDuration > stats[j].TotalDuration
+	})
+	return stats
+}
+
+// queryShape normalises a statement for logging and grouping: literals are
+// replaced with "?" and whitespace is collapsed, so no inline values leak.
+func queryShape(sql string) string {
+	shape := stringLiteral.ReplaceAllString(sql, "?")
+	shape = numericLiteral.ReplaceAllString(shape, "${1}?")
+	shape = strings.TrimSpace(whitespace.ReplaceAllString(shape, " "))
+	if len(shape) > maxShapeLength {
+		shape = shape[:maxShapeLength] + "..."
+	}
+	return shape
+}
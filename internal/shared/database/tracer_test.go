@@ -0,0 +1,158 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	loggerConfig "github.com/goku-m/main/internal/shared/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestQueryShape(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"integer", "SELECT * FROM tasks WHERE id = 42", "SELECT * FROM tasks WHERE id = ?"},
+		{"decimal", "SELECT * FROM items WHERE price > 12.50", "SELECT * FROM items WHERE price > ?"},
+		{"string", "SELECT * FROM users WHERE name = 'alice'", "SELECT * FROM users WHERE name = ?"},
+		{"escaped quote", "SELECT * FROM users WHERE name = 'O''Brien'", "SELECT * FROM users WHERE name = ?"},
+		{"limit and offset", "SELECT * FROM tasks LIMIT 10 OFFSET 20", "SELECT * FROM tasks LIMIT ? OFFSET ?"},
+		{"placeholders are kept", "SELECT * FROM tasks WHERE id = $1 AND owner = $12", "SELECT * FROM tasks WHERE id = $1 AND owner = $12"},
+		{"identifiers are kept", "SELECT t1.col2 FROM table3 t1", "SELECT t1.col2 FROM table3 t1"},
+		{"whitespace", "SELECT id\n\tFROM   tasks\n", "SELECT id FROM tasks"},
+		{"same shape for other values", "SELECT * FROM tasks WHERE id = 7", "SELECT * FROM tasks WHERE id = ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryShape(tt.sql); got != tt.want {
+				t.Errorf("queryShape(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryShapeTruncatesLongStatements(t *testing.T) {
+	shape := queryShape("SELECT " + strings.Repeat("a", 2*maxShapeLength))
+	if len(shape) != maxShapeLength+len("...") || !strings.HasSuffix(shape, "...") {
+		t.Errorf("shape has length %d, want %d ending in ...", len(shape), maxShapeLength+3)
+	}
+}
+
+// traceQuery runs sql through the tracer as if it took the given duration.
+func traceQuery(ctx context.Context, tracer *QueryTracer, sql string, took time.Duration, err error) {
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{1}})
+	trace := ctx.Value(traceKey{}).(traceData)
+	trace.start = trace.start.Add(-took)
+	ctx = context.WithValue(ctx, traceKey{}, trace)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: err})
+}
+
+func TestQueryTracerSlowQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		took      time.Duration
+		wantLog   bool
+	}{
+		{"faster than threshold", 100 * time.Millisecond, 10 * time.Millisecond, false},
+		{"slower than threshold", 100 * time.Millisecond, 200 * time.Millisecond, true},
+		{"threshold disabled", 0, time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := zerolog.New(&logs)
+			tracer := NewQueryTracer(&logger, tt.threshold)
+
+			ctx := loggerConfig.WithRequestID(context.Background(), "req-1")
+			traceQuery(ctx, tracer, "SELECT * FROM users WHERE email = 'alice@example.com'", tt.took, nil)
+
+			logged := strings.Contains(logs.String(), "slow query")
+			if logged != tt.wantLog {
+				t.Fatalf("logged slow query = %v, want %v: %s", logged, tt.wantLog, logs.String())
+			}
+			if logged {
+				for _, want := range []string{`"sql":"SELECT * FROM users WHERE email = ?"`, `"request_id":"req-1"`, `"args":1`} {
+					if !strings.Contains(logs.String(), want) {
+						t.Errorf("log %s misses %s", logs.String(), want)
+					}
+				}
+				if strings.Contains(logs.String(), "alice@example.com") {
+					t.Errorf("log leaks a literal: %s", logs.String())
+				}
+			}
+
+			stats := tracer.Stats()
+			wantSlow := int64(0)
+			if tt.wantLog {
+				wantSlow = 1
+			}
+			if len(stats) != 1 || stats[0].Slow != wantSlow {
+				t.Errorf("stats = %+v, want one shape with %d slow", stats, wantSlow)
+			}
+		})
+	}
+}
+
+func TestQueryTracerStatsPerShape(t *testing.T) {
+	logger := zerolog.Nop()
+	tracer := NewQueryTracer(&logger, 50*time.Millisecond)
+	ctx := context.Background()
+
+	traceQuery(ctx, tracer, "SELECT * FROM tasks WHERE id = 1", 10*time.Millisecond, nil)
+	traceQuery(ctx, tracer, "SELECT * FROM tasks WHERE id = 2", 100*time.Millisecond, nil)
+	traceQuery(ctx, tracer, "SELECT * FROM tasks WHERE id = 3", 20*time.Millisecond, errors.New("boom"))
+	traceQuery(ctx, tracer, "SELECT 1", time.Millisecond, nil)
+
+	stats := tracer.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v, want 2 shapes", stats)
+	}
+
+	tasks := stats[0]
+	if tasks.Query != "SELECT * FROM tasks WHERE id = ?" {
+		t.Fatalf("slowest shape = %q, want the tasks query first", tasks.Query)
+	}
+	if tasks.Count != 3 || tasks.Errors != 1 || tasks.Slow != 1 {
+		t.Errorf("count %d errors %d slow %d, want 3, 1, 1", tasks.Count, tasks.Errors, tasks.Slow)
+	}
+	if tasks.TotalDuration < 130*time.Millisecond || tasks.MaxDuration < 100*time.Millisecond {
+		t.Errorf("total %s max %s, want at least 130ms and 100ms", tasks.TotalDuration, tasks.MaxDuration)
+	}
+	if stats[1].Query != "SELECT ?" || stats[1].Count != 1 {
+		t.Errorf("second shape = %+v, want SELECT ? once", stats[1])
+	}
+}
+
+func TestQueryTracerGroupsShapesBeyondLimit(t *testing.T) {
+	logger := zerolog.Nop()
+	tracer := NewQueryTracer(&logger, 0)
+
+	for i := range maxTrackedQueries + 2 {
+		traceQuery(context.Background(), tracer, fmt.Sprintf("SELECT * FROM table_%c%d", 'a'+i%26, i/26), 0, nil)
+	}
+
+	stats := tracer.Stats()
+	if len(stats) != maxTrackedQueries+1 {
+		t.Fatalf("%d shapes tracked, want %d", len(stats), maxTrackedQueries+1)
+	}
+	for _, stat := range stats {
+		if stat.Query == otherQueries {
+			if stat.Count != 2 {
+				t.Errorf("%s counted %d times, want 2", otherQueries, stat.Count)
+			}
+			return
+		}
+	}
+	t.Errorf("no %q shape after %d distinct statements", otherQueries, maxTrackedQueries+2)
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/sqlerr"
+	"github.com/jackc/pgx/v5"
+)
+
+// Retry settings for transactions that fail with a serialization failure or
+// a deadlock.
+const (
+	DefaultTxAttempts = 3
+	txRetryBackoff    = 20 * time.Millisecond
+)
+
+// TxFunc is the body of a transaction. Statements must use the ctx it is
+// given, through Reader or Writer, to run inside the transaction.
+type TxFunc func(ctx context.Context) error
+
+// InTx runs fn in a transaction on the primary, committing when fn returns
+// nil and rolling back otherwise, including on panic.
+//
+// Called with a ctx that already carries a transaction, fn runs in a
+// savepoint of it instead: an error rolls back only fn's work and is returned
+// to the enclosing fn.
+//
+// A transaction failing with a serialization failure or a deadlock is run
+// again, up to DefaultTxAttempts times, so fn must not have side effects
+// outside the database.
+func (db *Database) InTx(ctx context.Context, fn TxFunc) error {
+	return db.InTxWithOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// InTxWithOptions is InTx with an isolation level or access mode. The options
+// are ignored for savepoints, which inherit them from their transaction.
+func (db *Database) InTxWithOptions(ctx context.Context, opts pgx.TxOptions, fn TxFunc) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return savepoint(ctx, tx, fn)
+	}
+
+	markWritten(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := db.runTx(ctx, opts, fn)
+		if err == nil || !sqlerr.IsRetryable(err) || attempt >= DefaultTxAttempts {
+			return err
+		}
+
+		backoff := txRetryBackoff*time.Duration(attempt) + rand.N(txRetryBackoff)
+		db.log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retry_in", backoff).
+			Msg("transaction conflict, retrying")
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (db *Database) runTx(ctx context.Context, opts pgx.TxOptions, fn TxFunc) error {
+	begin := db.beginTx
+	if begin == nil {
+		begin = db.Pool.BeginTx
+	}
+	tx, err := begin(db.scope.context(ctx), opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// No-op once committed
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if err := fn(WithTx(ctx, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func savepoint(ctx context.Context, tx pgx.Tx, fn TxFunc) error {
+	// Begin on a pgx.Tx creates a savepoint
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer sp.Rollback(context.WithoutCancel(ctx))
+
+	if err := fn(WithTx(ctx, sp)); err != nil {
+		return err
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
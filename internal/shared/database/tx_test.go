@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/goku-m/main/internal/shared/sqlerr"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// fakeTx records Begin, Commit and Rollback calls in calls. Like pgx, a
+// rollback after commit is a no-op. Other pgx.Tx methods are not used by InTx
+// and panic.
+type fakeTx struct {
+	pgx.Tx
+	name      string
+	calls     *[]string
+	commitErr error
+	done      bool
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	sp := &fakeTx{name: tx.name + "/savepoint", calls: tx.calls}
+	*tx.calls = append(*tx.calls, "begin "+sp.name)
+	return sp, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	*tx.calls = append(*tx.calls, "commit "+tx.name)
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	*tx.calls = append(*tx.calls, "rollback "+tx.name)
+	return nil
+}
+
+// newTxDatabase returns a database whose transactions are fakeTx values
+// named tx, recording their calls in calls. commitErrs fail the commits of
+// the first transactions in turn.
+func newTxDatabase(calls *[]string, commitErrs ...error) *Database {
+	logger := zerolog.Nop()
+	begun := 0
+	return &Database{
+		log: &logger,
+		beginTx: func(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+			tx := &fakeTx{name: "tx", calls: calls}
+			if begun < len(commitErrs) {
+				tx.commitErr = commitErrs[begun]
+			}
+			begun++
+			*calls = append(*calls, "begin tx")
+			return tx, nil
+		},
+	}
+}
+
+func TestInTxCommitsAndRollsBack(t *testing.T) {
+	errFn := errors.New("fn failed")
+
+	tests := []struct {
+		name      string
+		fn        TxFunc
+		wantErr   error
+		wantCalls []string
+	}{
+		{
+			name:      "commit",
+			fn:        func(ctx context.Context) error { return nil },
+			wantCalls: []string{"begin tx", "commit tx"},
+		},
+		{
+			name:      "rollback on error",
+			fn:        func(ctx context.Context) error { return errFn },
+			wantErr:   errFn,
+			wantCalls: []string{"begin tx", "rollback tx"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			db := newTxDatabase(&calls)
+
+			err := db.InTx(context.Background(), func(ctx context.Context) error {
+				tx, ok := TxFromContext(ctx)
+				if !ok {
+					t.Fatal("no transaction on ctx")
+				}
+				if db.Reader(ctx) != Querier(tx) || db.Writer(ctx) != Querier(tx) {
+					t.Error("Reader and Writer do not use the transaction")
+				}
+				return tt.fn(ctx)
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestInTxRollsBackOnPanic(t *testing.T) {
+	var calls []string
+	db := newTxDatabase(&calls)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not propagated")
+			}
+		}()
+		_ = db.InTx(context.Background(), func(ctx context.Context) error {
+			panic("boom")
+		})
+	}()
+
+	if want := []string{"begin tx", "rollback tx"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestInTxNestedRunsInSavepoint(t *testing.T) {
+	errInner := errors.New("inner failed")
+
+	tests := []struct {
+		name      string
+		innerErr  error
+		wantCalls []string
+	}{
+		{
+			name:      "inner commits",
+			wantCalls: []string{"begin tx", "begin tx/savepoint", "commit tx/savepoint", "commit tx"},
+		},
+		{
+			name:      "inner error rolls back only the savepoint",
+			innerErr:  errInner,
+			wantCalls: []string{"begin tx", "begin tx/savepoint", "rollback tx/savepoint", "commit tx"},
+		},
+		{
+			name:      "inner conflict is not retried in the savepoint",
+			innerErr:  &pgconn.PgError{Code: "40001"},
+			wantCalls: []string{"begin tx", "begin tx/savepoint", "rollback tx/savepoint", "commit tx"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			db := newTxDatabase(&calls)
+
+			err := db.InTx(context.Background(), func(ctx context.Context) error {
+				outer, _ := TxFromContext(ctx)
+
+				innerErr := db.InTx(ctx, func(ctx context.Context) error {
+					if inner, _ := TxFromContext(ctx); inner == outer {
+						t.Error("nested InTx runs on the outer transaction, not a savepoint")
+					}
+					return tt.innerErr
+				})
+				if !errors.Is(innerErr, tt.innerErr) {
+					t.Errorf("inner err = %v, want %v", innerErr, tt.innerErr)
+				}
+				// The enclosing transaction handles the error and goes on
+				return nil
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestInTxRetriesConflicts(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001"}
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	unique := &pgconn.PgError{Code: "23505"}
+
+	tests := []struct {
+		name         string
+		fnErrs       []error
+		commitErrs   []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"serialization failure", []error{serialization}, nil, 2, nil},
+		{"deadlock", []error{deadlock, deadlock}, nil, 3, nil},
+		{"mapped serialization failure", []error{sqlerr.ConvertPgError(serialization)}, nil, 2, nil},
+		{"serialization failure on commit", nil, []error{serialization}, 2, nil},
+		{"gives up after DefaultTxAttempts", []error{deadlock, deadlock, deadlock, deadlock}, nil, DefaultTxAttempts, deadlock},
+		{"other errors are not retried", []error{unique}, nil, 1, unique},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			db := newTxDatabase(&calls, tt.commitErrs...)
+
+			attempts := 0
+			err := db.InTx(context.Background(), func(ctx context.Context) error {
+				attempts++
+				if attempts <= len(tt.fnErrs) {
+					return tt.fnErrs[attempts-1]
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("fn ran %d times, want %d", attempts, tt.wantAttempts)
+			}
+			begun := 0
+			for _, call := range calls {
+				if call == "begin tx" {
+					begun++
+				}
+			}
+			if begun != tt.wantAttempts {
+				t.Errorf("calls = %v, want %d transactions", calls, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestInTxStopsRetryingWhenContextEnds(t *testing.T) {
+	var calls []string
+	db := newTxDatabase(&calls)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := db.InTx(ctx, func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return &pgconn.PgError{Code: "40001"}
+	})
+
+	if !errors.Is(err, context.Canceled) || !sqlerr.IsRetryable(err) {
+		t.Errorf("err = %v, want the conflict joined with context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("fn ran %d times after cancel, want 1", attempts)
+	}
+}
@@ -54,6 +54,28 @@ func NewNotFoundError(message string, override bool, code *string) *HTTPError {
 	}
 }
 
+// NewConflictError reports a write based on a stale version of a resource.
+func NewConflictError(message string, override bool) *HTTPError {
+	return &HTTPError{
+		Code:     MakeUpperCaseWithUnderscores(http.StatusText(http.StatusConflict)),
+		Message:  message,
+		Status:   http.StatusConflict,
+		Override: override,
+	}
+}
+
+// NewPreconditionFailedError reports a request whose If-Match precondition
+// does not hold for the current version of a resource. It shares the code of
+// NewConflictError, so clients handle both stale writes the same way.
+func NewPreconditionFailedError(message string, override bool) *HTTPError {
+	return &HTTPError{
+		Code:     MakeUpperCaseWithUnderscores(http.StatusText(http.StatusConflict)),
+		Message:  message,
+		Status:   http.StatusPreconditionFailed,
+		Override: override,
+	}
+}
+
 func NewInternalServerError() *HTTPError {
 	return &HTTPError{
 		Code:     MakeUpperCaseWithUnderscores(http.StatusText(http.StatusInternalServerError)),
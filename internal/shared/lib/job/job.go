@@ -1,6 +1,8 @@
 package job
 
 import (
+	"sync"
+
 	"github.com/goku-m/main/internal/shared/config"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
@@ -9,7 +11,11 @@ import (
 type JobService struct {
 	Client *asynq.Client
 	server *asynq.Server
+	mux    *asynq.ServeMux
 	logger *zerolog.Logger
+
+	handlersMu sync.Mutex
+	handlers   map[string]bool
 }
 
 func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
@@ -31,20 +37,37 @@ func NewJobService(logger *zerolog.Logger, cfg *config.Config) *JobService {
 		},
 	)
 
-	return &JobService{
-		Client: client,
-		server: server,
-		logger: logger,
+	j := &JobService{
+		Client:   client,
+		server:   server,
+		mux:      asynq.NewServeMux(),
+		logger:   logger,
+		handlers: make(map[string]bool),
 	}
-}
 
-func (j *JobService) Start() error {
 	// Register task handlers
-	mux := asynq.NewServeMux()
-	mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
+	j.Handle(TaskWelcome, j.handleWelcomeEmailTask)
+
+	return j
+}
 
+// Handle registers the handler of a task type. Modules register theirs while
+// they are built, which may be after Start. The first handler registered for
+// a type is kept, so canary variants of a module can register the same types.
+func (j *JobService) Handle(taskType string, handler asynq.HandlerFunc) {
+	j.handlersMu.Lock()
+	defer j.handlersMu.Unlock()
+
+	if j.handlers[taskType] {
+		return
+	}
+	j.handlers[taskType] = true
+	j.mux.HandleFunc(taskType, handler)
+}
+
+func (j *JobService) Start() error {
 	j.logger.Info().Msg("Starting background job server")
-	if err := j.server.Start(mux); err != nil {
+	if err := j.server.Start(j.mux); err != nil {
 		return err
 	}
 
@@ -0,0 +1,17 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID in ctx, so code below the HTTP layer,
+// such as the database tracer, can tag its logs with it.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -5,29 +5,52 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
 
 	"github.com/goku-m/main/internal/shared/config"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
 )
 
-// NewLogger creates a logger with full config.
-func NewLogger(cfg *config.ObservabilityConfig) zerolog.Logger {
-	var logLevel zerolog.Level
-	level := cfg.GetLogLevel()
+// minLevel is the runtime log level of every logger created by NewLogger,
+// including loggers derived from it. SetLevel changes it without a restart.
+var minLevel atomic.Int32
+
+// levelHook drops events below minLevel.
+type levelHook struct{}
+
+func (levelHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level != zerolog.NoLevel && level < zerolog.Level(minLevel.Load()) {
+		e.Discard()
+	}
+}
 
+// ParseLevel maps a configured level name to a zerolog level, defaulting to
+// info for unknown names.
+func ParseLevel(level string) zerolog.Level {
 	switch level {
 	case "debug":
-		logLevel = zerolog.DebugLevel
+		return zerolog.DebugLevel
 	case "info":
-		logLevel = zerolog.InfoLevel
+		return zerolog.InfoLevel
 	case "warn":
-		logLevel = zerolog.WarnLevel
+		return zerolog.WarnLevel
 	case "error":
-		logLevel = zerolog.ErrorLevel
+		return zerolog.ErrorLevel
 	default:
-		logLevel = zerolog.InfoLevel
+		return zerolog.InfoLevel
 	}
+}
+
+// SetLevel changes the level of all loggers created by NewLogger.
+func SetLevel(level zerolog.Level) {
+	minLevel.Store(int32(level))
+}
+
+// NewLogger creates a logger with full config. Its level is controlled by
+// SetLevel, so it can be changed when the config is reloaded.
+func NewLogger(cfg *config.ObservabilityConfig) zerolog.Logger {
+	SetLevel(ParseLevel(cfg.GetLogLevel()))
 
 	// Don't set global level - let each logger have its own level
 	zerolog.TimeFieldFormat = "2006-01-02 15:04:05"
@@ -48,7 +71,7 @@ func NewLogger(cfg *config.ObservabilityConfig) zerolog.Logger {
 	}
 
 	logger := zerolog.New(writer).
-		Level(logLevel).
+		Hook(levelHook{}).
 		With().
 		Timestamp().
 		Str("service", cfg.ServiceName).
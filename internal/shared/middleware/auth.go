@@ -18,7 +18,7 @@ func NewAuthMiddleware(s *server.Server) *AuthMiddleware {
 func (auth *AuthMiddleware) RequireAuthIP(next echo.HandlerFunc) echo.HandlerFunc {
 	return (func(c echo.Context) error {
 
-		c.Set("user_id", c.RealIP())
+		SetUser(c, c.RealIP(), "")
 
 		return next(c)
 	})
@@ -60,8 +60,7 @@ func (auth *AuthMiddleware) RequireAuthIP(next echo.HandlerFunc) echo.HandlerFun
 // 			return errs.NewUnauthorizedError("Unauthorized", false)
 // 		}
 
-// 		c.Set("user_id", claims.Subject)
-// 		c.Set("user_role", claims.ActiveOrganizationRole)
+// 		SetUser(c, claims.Subject, claims.ActiveOrganizationRole)
 // 		c.Set("permissions", claims.Claims.ActiveOrganizationPermissions)
 
 // 		auth.server.Logger.Info().
@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"context"
+	"sync"
 
+	"github.com/goku-m/main/internal/shared/audit"
+	"github.com/goku-m/main/internal/shared/database"
 	"github.com/goku-m/main/internal/shared/server"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
@@ -12,8 +15,50 @@ const (
 	UserIDKey   = "user_id"
 	UserRoleKey = "user_role"
 	LoggerKey   = "logger"
+
+	// Set by the gateway once it has resolved the module serving a request.
+	ModuleKey  = "module"
+	VariantKey = "variant"
 )
 
+// requestUser carries the user identified by a module's auth middleware back
+// to the gateway pipeline. Modules serve the request on their own
+// echo.Context, so values they set there are gone once the gateway logs the
+// request.
+type requestUser struct {
+	mu   sync.Mutex
+	id   string
+	role string
+}
+
+type requestUserKey struct{}
+
+func userFromContext(ctx context.Context) (id, role string) {
+	u, ok := ctx.Value(requestUserKey{}).(*requestUser)
+	if !ok {
+		return "", ""
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.id, u.role
+}
+
+// SetUser records the authenticated user of the request. Auth middleware
+// must use it rather than setting UserIDKey directly, so the gateway's
+// request log also sees the user.
+func SetUser(c echo.Context, userID, userRole string) {
+	c.Set(UserIDKey, userID)
+	if userRole != "" {
+		c.Set(UserRoleKey, userRole)
+	}
+
+	if u, ok := c.Request().Context().Value(requestUserKey{}).(*requestUser); ok {
+		u.mu.Lock()
+		u.id, u.role = userID, userRole
+		u.mu.Unlock()
+	}
+}
+
 type ContextEnhancer struct {
 	server *server.Server
 }
@@ -32,7 +77,7 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 			contextLogger := ce.server.Logger.With().
 				Str("request_id", requestID).
 				Str("method", c.Request().Method).
-				Str("path", c.Path()).
+				Str("path", c.Request().URL.Path).
 				Str("ip", c.RealIP()).
 				Logger()
 
@@ -45,11 +90,28 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 				contextLogger = contextLogger.With().Str("user_role", userRole).Logger()
 			}
 
+			if module, ok := c.Get(ModuleKey).(string); ok && module != "" {
+				contextLogger = contextLogger.With().Str("module", module).Logger()
+			}
+
+			if variant, ok := c.Get(VariantKey).(string); ok && variant != "" {
+				contextLogger = contextLogger.With().Str("variant", variant).Logger()
+			}
+
 			// Store the enhanced logger in context
 			c.Set(LoggerKey, &contextLogger)
 
 			// Create a new context with the logger
 			ctx := context.WithValue(c.Request().Context(), LoggerKey, &contextLogger)
+			// Packages that only take a context read it with zerolog.Ctx
+			ctx = contextLogger.WithContext(ctx)
+
+			// Scope replica reads to the request: after a write, its reads
+			// stay on the primary.
+			ctx = database.WithSession(ctx)
+
+			// Let the module serving the request report its user back
+			ctx = context.WithValue(ctx, requestUserKey{}, &requestUser{})
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
@@ -58,32 +120,42 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 }
 
 func (ce *ContextEnhancer) extractUserID(c echo.Context) string {
-	// Check if user_id was already set by auth middleware (Clerk)
-	if userID, ok := c.Get("user_id").(string); ok && userID != "" {
-		return userID
-	}
-	return ""
+	return GetUserID(c)
 }
 
 func (ce *ContextEnhancer) extractUserRole(c echo.Context) string {
 	// Check if user_role was set by auth middleware (Clerk)
-	if userRole, ok := c.Get("user_role").(string); ok && userRole != "" {
+	if userRole, ok := c.Get(UserRoleKey).(string); ok && userRole != "" {
 		return userRole
 	}
-	return ""
+	_, userRole := userFromContext(c.Request().Context())
+	return userRole
 }
 
+// GetUserID returns the user recorded with SetUser, also when it was set by
+// the module router serving the request behind the gateway.
 func GetUserID(c echo.Context) string {
-	if userID, ok := c.Get(UserIDKey).(string); ok {
+	if userID, ok := c.Get(UserIDKey).(string); ok && userID != "" {
 		return userID
 	}
-	return ""
+	userID, _ := userFromContext(c.Request().Context())
+	return userID
+}
+
+// AuditContext returns the request context with the user as the actor of
+// the changes written with it, for repository calls that write.
+func AuditContext(c echo.Context) context.Context {
+	return audit.WithActor(c.Request().Context(), GetUserID(c))
 }
 
 func GetLogger(c echo.Context) *zerolog.Logger {
 	if logger, ok := c.Get(LoggerKey).(*zerolog.Logger); ok {
 		return logger
 	}
+	// Module routers behind the gateway only see the request context
+	if logger, ok := c.Request().Context().Value(LoggerKey).(*zerolog.Logger); ok {
+		return logger
+	}
 	// Fallback to a basic logger if not found
 	logger := zerolog.Nop()
 	return &logger
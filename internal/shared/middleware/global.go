@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"sync/atomic"
 
 	"github.com/goku-m/main/internal/shared/errs"
 	"github.com/goku-m/main/internal/shared/server"
@@ -13,18 +15,34 @@ import (
 )
 
 type GlobalMiddlewares struct {
-	server *server.Server
+	server      *server.Server
+	corsOrigins atomic.Pointer[[]string]
 }
 
 func NewGlobalMiddlewares(s *server.Server) *GlobalMiddlewares {
-	return &GlobalMiddlewares{
+	global := &GlobalMiddlewares{
 		server: s,
 	}
+	global.SetCORSOrigins(s.Config.Server.CORSAllowedOrigins)
+	return global
 }
 
+// SetCORSOrigins replaces the allowed CORS origins of running middleware.
+func (global *GlobalMiddlewares) SetCORSOrigins(origins []string) {
+	global.corsOrigins.Store(&origins)
+}
+
+// CORS allows the origins set through SetCORSOrigins. An empty list allows
+// every origin.
 func (global *GlobalMiddlewares) CORS() echo.MiddlewareFunc {
 	return middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: global.server.Config.Server.CORSAllowedOrigins,
+		AllowOriginFunc: func(origin string) (bool, error) {
+			origins := *global.corsOrigins.Load()
+			if len(origins) == 0 {
+				return true, nil
+			}
+			return slices.Contains(origins, origin) || slices.Contains(origins, "*"), nil
+		},
 	})
 }
 
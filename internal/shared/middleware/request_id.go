@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/goku-m/main/internal/shared/logger"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
@@ -21,6 +22,12 @@ func RequestID() echo.MiddlewareFunc {
 			c.Set(RequestIDKey, requestID)
 			c.Response().Header().Set(RequestIDHeader, requestID)
 
+			// Also carry the ID on the request context so module routers
+			// mounted behind the gateway, which get their own echo.Context,
+			// can still read it, and so can the database tracer.
+			ctx := logger.WithRequestID(c.Request().Context(), requestID)
+			c.SetRequest(c.Request().WithContext(ctx))
+
 			return next(c)
 		}
 	}
@@ -30,5 +37,5 @@ func GetRequestID(c echo.Context) string {
 	if requestID, ok := c.Get(RequestIDKey).(string); ok {
 		return requestID
 	}
-	return ""
+	return logger.RequestIDFromContext(c.Request().Context())
 }
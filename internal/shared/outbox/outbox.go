@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/goku-m/main/internal/shared/database"
+)
+
+// DefaultQueue is the job queue of messages that do not set one.
+const DefaultQueue = "default"
+
+// ErrNoTransaction is returned by Add outside a transaction: a message is
+// only reliable if it commits or rolls back with the change it reports.
+var ErrNoTransaction = errors.New("outbox messages must be added inside a transaction")
+
+// Message is a side effect of a domain change, delivered to the job queue as
+// a task of type Type once the change has committed. Messages of the same
+// aggregate are delivered in the order they were added.
+type Message struct {
+	AggregateType string
+	AggregateID   string
+	Type          string
+	Payload       any
+	Queue         string
+}
+
+// Add writes messages to the outbox of the module owning db, in the
+// transaction carried on ctx (see database.InTx).
+func Add(ctx context.Context, db *database.Database, msgs ...Message) error {
+	if _, ok := database.TxFromContext(ctx); !ok {
+		return ErrNoTransaction
+	}
+
+	for _, msg := range msgs {
+		payload, err := json.Marshal(msg.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox payload of %s: %w", msg.Type, err)
+		}
+
+		queue := msg.Queue
+		if queue == "" {
+			queue = DefaultQueue
+		}
+
+		_, err = db.Writer(ctx).Exec(ctx, `
+			INSERT INTO
+				outbox (module, aggregate_type, aggregate_id, event_type, payload, queue)
+			VALUES
+				($1, $2, $3, $4, $5, $6)
+		`, db.Schema(), msg.AggregateType, msg.AggregateID, msg.Type, payload, queue)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to outbox for %s=%s: %w", msg.Type, msg.AggregateType, msg.AggregateID, err)
+		}
+	}
+
+	return nil
+}
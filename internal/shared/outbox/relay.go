@@ -0,0 +1,230 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/lib/job"
+	"github.com/goku-m/main/internal/shared/worker"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// Relay settings.
+const (
+	PollInterval     = time.Second
+	BatchSize        = 100
+	Retention        = 7 * 24 * time.Hour
+	ClaimLease       = time.Minute
+	CleanupInterval  = time.Hour
+	maxRetryBackoff  = 5 * time.Minute
+	baseRetryBackoff = time.Second
+)
+
+// Relay drains the outbox of one module into the job queue. Every message is
+// delivered at least once: a batch claims its messages for ClaimLease, enqueues
+// them without holding row locks and marks each one published after its
+// enqueue succeeded. Messages of a relay that died are claimed again once the
+// lease expires. Several relays, e.g. one per replica, can drain the same
+// module; each claims different aggregates. Published messages are deleted
+// after Retention.
+type Relay struct {
+	store   store
+	enqueue func(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+	logger  *zerolog.Logger
+	module  string
+	worker  *worker.Worker
+
+	// Only touched by the worker's goroutine.
+	lastCleanup time.Time
+}
+
+// NewRelay returns the relay of the module owning db.
+func NewRelay(db *database.Database, jobs *job.JobService, logger *zerolog.Logger) *Relay {
+	r := &Relay{
+		store:   dbStore{db: db, module: db.Schema()},
+		enqueue: jobs.Client.EnqueueContext,
+		logger:  logger,
+		module:  db.Schema(),
+	}
+	r.worker = worker.Every("outbox relay", PollInterval, r.poll)
+	return r
+}
+
+// Start launches the relay loop. The context only bounds startup; the loop
+// runs until Stop is called.
+func (r *Relay) Start(ctx context.Context) error {
+	return r.worker.Start(ctx)
+}
+
+// Stop cancels the relay loop and waits for it to exit or for ctx to expire.
+// Messages claimed by an unfinished batch are relayed again once their lease
+// expires.
+func (r *Relay) Stop(ctx context.Context) error {
+	return r.worker.Stop(ctx)
+}
+
+// poll relays the pending messages and deletes old published ones once per
+// CleanupInterval.
+func (r *Relay) poll(ctx context.Context) {
+	// Keep draining while there is work, since every batch holds at most one
+	// message per aggregate.
+	for {
+		published, err := r.relayBatch(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				r.logger.Error().Err(err).Str("module", r.module).Msg("failed to relay outbox messages")
+			}
+			break
+		}
+		if published == 0 {
+			break
+		}
+	}
+
+	if time.Since(r.lastCleanup) >= CleanupInterval {
+		r.lastCleanup = time.Now()
+		r.cleanup(ctx)
+	}
+}
+
+// pending is a claimed outbox row.
+type pending struct {
+	id            int64
+	aggregateType string
+	aggregateID   string
+	eventType     string
+	payload       []byte
+	queue         string
+	attempts      int
+}
+
+// relayBatch delivers the oldest pending message of up to BatchSize
+// aggregates and returns how many were published. Later messages of an
+// aggregate wait until the ones before them are published, which keeps each
+// aggregate in order even while a delivery is retried.
+//
+// A delivery failing because the job queue cannot be reached ends the batch:
+// the remaining messages are released for the next poll rather than each
+// waiting for the same failure.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	batch, err := r.store.claim(ctx, BatchSize, ClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i, p := range batch {
+		deliverErr := r.deliver(ctx, p)
+		if deliverErr == nil {
+			if err := r.store.markPublished(ctx, p.id); err != nil {
+				return published, err
+			}
+			published++
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return published, ctx.Err()
+		}
+		if err := r.retryLater(ctx, p, deliverErr); err != nil {
+			return published, err
+		}
+
+		if isConnectionError(deliverErr) {
+			rest := make([]int64, 0, len(batch)-i-1)
+			for _, p := range batch[i+1:] {
+				rest = append(rest, p.id)
+			}
+			if len(rest) > 0 {
+				if err := r.store.release(ctx, rest); err != nil {
+					return published, err
+				}
+			}
+			return published, fmt.Errorf("job queue unavailable: %w", deliverErr)
+		}
+	}
+
+	return published, nil
+}
+
+// isConnectionError reports whether an enqueue failed because Redis could not
+// be reached, as opposed to a problem with the message itself.
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, redis.ErrClosed)
+}
+
+// deliver enqueues the message. The task ID is derived from the row, so a
+// message enqueued again after a failed commit is not queued twice while
+// asynq still holds the first copy.
+func (r *Relay) deliver(ctx context.Context, p pending) error {
+	task := asynq.NewTask(p.eventType, p.payload,
+		asynq.TaskID("outbox:"+r.module+":"+strconv.FormatInt(p.id, 10)),
+		asynq.Queue(p.queue),
+		asynq.MaxRetry(3),
+		asynq.Timeout(30*time.Second))
+
+	_, err := r.enqueue(ctx, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// retryLater records a failed delivery and backs the message off.
+func (r *Relay) retryLater(ctx context.Context, p pending, cause error) error {
+	backoff := retryBackoff(p.attempts)
+
+	r.logger.Warn().
+		Err(cause).
+		Str("module", r.module).
+		Int64("outbox_id", p.id).
+		Str("event", p.eventType).
+		Int("attempts", p.attempts+1).
+		Dur("retry_in", backoff).
+		Msg("failed to enqueue outbox message, retrying later")
+
+	return r.store.reschedule(ctx, p.id, cause.Error(), backoff)
+}
+
+// retryBackoff is the wait after a message failed for the attempts+1-th
+// time: it doubles from baseRetryBackoff up to maxRetryBackoff.
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 0 {
+		return baseRetryBackoff
+	}
+	if attempts >= 16 {
+		return maxRetryBackoff
+	}
+	return min(baseRetryBackoff<<attempts, maxRetryBackoff)
+}
+
+// cleanup deletes the module's messages published more than Retention ago.
+func (r *Relay) cleanup(ctx context.Context) {
+	n, err := r.store.deletePublished(ctx, Retention)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			r.logger.Error().Err(err).Str("module", r.module).Msg("failed to clean up outbox")
+		}
+		return
+	}
+
+	if n > 0 {
+		r.logger.Info().
+			Str("module", r.module).
+			Int64("deleted", n).
+			Msg("cleaned up published outbox messages")
+	}
+}
@@ -0,0 +1,231 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+// memStore is an in-memory outbox following the claim rules of dbStore,
+// on a clock the test moves forward.
+type memStore struct {
+	now      time.Time
+	messages []*memMessage
+}
+
+type memMessage struct {
+	pending
+	availableAt time.Time
+	published   bool
+	lastError   string
+}
+
+func (s *memStore) add(aggregateID, payload string) {
+	s.messages = append(s.messages, &memMessage{
+		pending: pending{
+			id:            int64(len(s.messages) + 1),
+			aggregateType: "task",
+			aggregateID:   aggregateID,
+			eventType:     "task:updated",
+			payload:       []byte(payload),
+			queue:         DefaultQueue,
+		},
+		availableAt: s.now,
+	})
+}
+
+func (s *memStore) get(id int64) *memMessage {
+	return s.messages[id-1]
+}
+
+func (s *memStore) claim(ctx context.Context, limit int, lease time.Duration) ([]pending, error) {
+	var batch []pending
+	blocked := map[string]bool{}
+	for _, m := range s.messages {
+		if m.published {
+			continue
+		}
+		if !blocked[m.aggregateID] && !m.availableAt.After(s.now) && len(batch) < limit {
+			m.availableAt = s.now.Add(lease)
+			batch = append(batch, m.pending)
+		}
+		blocked[m.aggregateID] = true
+	}
+	return batch, nil
+}
+
+func (s *memStore) markPublished(ctx context.Context, id int64) error {
+	m := s.get(id)
+	m.published = true
+	m.attempts++
+	return nil
+}
+
+func (s *memStore) reschedule(ctx context.Context, id int64, cause string, backoff time.Duration) error {
+	m := s.get(id)
+	m.attempts++
+	m.lastError = cause
+	m.availableAt = s.now.Add(backoff)
+	return nil
+}
+
+func (s *memStore) release(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		s.get(id).availableAt = s.now
+	}
+	return nil
+}
+
+func (s *memStore) deletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// fakeQueue records the payloads enqueued and fails those listed in fail,
+// once each.
+type fakeQueue struct {
+	enqueued []string
+	attempts int
+	fail     map[string]error
+}
+
+func (q *fakeQueue) enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	q.attempts++
+	payload := string(task.Payload())
+	if err, ok := q.fail[payload]; ok {
+		delete(q.fail, payload)
+		return nil, err
+	}
+	q.enqueued = append(q.enqueued, payload)
+	return &asynq.TaskInfo{}, nil
+}
+
+func newTestRelay(store *memStore, queue *fakeQueue) *Relay {
+	logger := zerolog.Nop()
+	return &Relay{store: store, enqueue: queue.enqueue, logger: &logger, module: "task"}
+}
+
+func TestRelayKeepsAggregatesInOrder(t *testing.T) {
+	store := &memStore{now: time.Now()}
+	for _, m := range []struct{ aggregate, payload string }{
+		{"a", "a1"}, {"b", "b1"}, {"a", "a2"}, {"a", "a3"}, {"b", "b2"}, {"c", "c1"},
+	} {
+		store.add(m.aggregate, m.payload)
+	}
+	queue := &fakeQueue{fail: map[string]error{"a1": errors.New("rejected"), "b2": errors.New("rejected")}}
+	relay := newTestRelay(store, queue)
+
+	unpublished := func(m *memMessage) bool { return !m.published }
+	var batches [][]string
+	for round := 0; slices.ContainsFunc(store.messages, unpublished); round++ {
+		if round == 10 {
+			t.Fatalf("messages still pending after %d batches: %v", round, batches)
+		}
+		before := len(queue.enqueued)
+		if _, err := relay.relayBatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, slices.Clone(queue.enqueued[before:]))
+		// Past every backoff
+		store.now = store.now.Add(maxRetryBackoff)
+	}
+
+	want := [][]string{
+		// a1 fails and holds back a2 and a3; b1 and c1 go out
+		{"b1", "c1"},
+		// a1 is retried, b2 fails
+		{"a1"},
+		{"a2", "b2"},
+		{"a3"},
+	}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
+
+func TestRelayBacksOffFailedDelivery(t *testing.T) {
+	store := &memStore{now: time.Now()}
+	store.add("a", "a1")
+	store.add("b", "b1")
+	store.get(1).attempts = 2
+	queue := &fakeQueue{fail: map[string]error{"a1": errors.New("rejected")}}
+
+	published, err := newTestRelay(store, queue).relayBatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 || !slices.Equal(queue.enqueued, []string{"b1"}) {
+		t.Errorf("published %d %v, want b1 despite the failure of a1", published, queue.enqueued)
+	}
+
+	failed := store.get(1)
+	if failed.attempts != 3 || failed.lastError != "rejected" {
+		t.Errorf("attempts %d last error %q, want 3 and rejected", failed.attempts, failed.lastError)
+	}
+	if got := failed.availableAt.Sub(store.now); got != 4*time.Second {
+		t.Errorf("retried in %s, want 4s", got)
+	}
+}
+
+func TestRelayStopsBatchWhenQueueIsUnreachable(t *testing.T) {
+	store := &memStore{now: time.Now()}
+	store.add("a", "a1")
+	store.add("b", "b1")
+	store.add("c", "c1")
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	queue := &fakeQueue{fail: map[string]error{"a1": unreachable}}
+
+	published, err := newTestRelay(store, queue).relayBatch(context.Background())
+	if !errors.Is(err, unreachable) {
+		t.Fatalf("err = %v, want the connection error", err)
+	}
+	if published != 0 || queue.attempts != 1 {
+		t.Errorf("published %d after %d enqueues, want 0 after 1", published, queue.attempts)
+	}
+
+	if failed := store.get(1); failed.attempts != 1 || !failed.availableAt.After(store.now) {
+		t.Errorf("failed message attempts %d available at %s, want it backed off", failed.attempts, failed.availableAt)
+	}
+	for _, id := range []int64{2, 3} {
+		if m := store.get(id); m.attempts != 0 || !m.availableAt.Equal(store.now) {
+			t.Errorf("message %d attempts %d available at %s, want it released untried", id, m.attempts, m.availableAt)
+		}
+	}
+}
+
+func TestRelayTreatsTaskIDConflictAsDelivered(t *testing.T) {
+	store := &memStore{now: time.Now()}
+	store.add("a", "a1")
+	queue := &fakeQueue{fail: map[string]error{"a1": asynq.ErrTaskIDConflict}}
+
+	published, err := newTestRelay(store, queue).relayBatch(context.Background())
+	if err != nil || published != 1 || !store.get(1).published {
+		t.Errorf("published %d, err %v, want the message already queued to count as published", published, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{8, 256 * time.Second},
+		{9, maxRetryBackoff},
+		{16, maxRetryBackoff},
+		{100, maxRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
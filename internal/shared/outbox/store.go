@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+)
+
+// store is the relay's access to the outbox rows of its module.
+type store interface {
+	// claim leases the oldest pending message of up to limit aggregates, so
+	// no other relay picks them up before the lease expires.
+	claim(ctx context.Context, limit int, lease time.Duration) ([]pending, error)
+	markPublished(ctx context.Context, id int64) error
+	// reschedule records a failed delivery and makes the message available
+	// again after backoff.
+	reschedule(ctx context.Context, id int64, cause string, backoff time.Duration) error
+	// release hands claimed messages that were not attempted back at once.
+	release(ctx context.Context, ids []int64) error
+	deletePublished(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+// dbStore keeps the outbox in shared.outbox.
+type dbStore struct {
+	db     *database.Database
+	module string
+}
+
+func (s dbStore) claim(ctx context.Context, limit int, lease time.Duration) ([]pending, error) {
+	// A claimed message stays unpublished, so later messages of its aggregate
+	// keep waiting for it.
+	rows, err := s.db.Writer(ctx).Query(ctx, `
+		UPDATE outbox
+		SET
+			available_at = CURRENT_TIMESTAMP + $3::INTERVAL
+		WHERE
+			id IN (
+				SELECT
+					o.id
+				FROM
+					outbox o
+				WHERE
+					o.module = $1
+					AND o.published_at IS NULL
+					AND o.available_at <= CURRENT_TIMESTAMP
+					AND NOT EXISTS (
+						SELECT
+							1
+						FROM
+							outbox p
+						WHERE
+							p.module = o.module
+							AND p.aggregate_type = o.aggregate_type
+							AND p.aggregate_id = o.aggregate_id
+							AND p.published_at IS NULL
+							AND p.id < o.id
+					)
+				ORDER BY
+					o.id
+				LIMIT
+					$2
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING
+			id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			queue,
+			attempts
+	`, s.module, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.aggregateType, &p.aggregateID, &p.eventType, &p.payload, &p.queue, &p.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		batch = append(batch, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	// RETURNING has no order
+	slices.SortFunc(batch, func(a, b pending) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return batch, nil
+}
+
+func (s dbStore) markPublished(ctx context.Context, id int64) error {
+	_, err := s.db.Writer(ctx).Exec(ctx, `
+		UPDATE outbox
+		SET
+			published_at = CURRENT_TIMESTAMP,
+			attempts = attempts + 1,
+			last_error = NULL
+		WHERE
+			id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message %d published: %w", id, err)
+	}
+	return nil
+}
+
+func (s dbStore) reschedule(ctx context.Context, id int64, cause string, backoff time.Duration) error {
+	_, err := s.db.Writer(ctx).Exec(ctx, `
+		UPDATE outbox
+		SET
+			attempts = attempts + 1,
+			last_error = $2,
+			available_at = CURRENT_TIMESTAMP + $3::INTERVAL
+		WHERE
+			id = $1
+	`, id, cause, backoff)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbox message %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s dbStore) release(ctx context.Context, ids []int64) error {
+	_, err := s.db.Writer(ctx).Exec(ctx, `
+		UPDATE outbox
+		SET
+			available_at = CURRENT_TIMESTAMP
+		WHERE
+			id = ANY ($1)
+			AND published_at IS NULL
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to release outbox messages: %w", err)
+	}
+	return nil
+}
+
+func (s dbStore) deletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := s.db.Writer(ctx).Exec(ctx, `
+		DELETE FROM outbox
+		WHERE
+			module = $1
+			AND published_at < CURRENT_TIMESTAMP - $2::INTERVAL
+	`, s.module, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
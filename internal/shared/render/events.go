@@ -0,0 +1,60 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/labstack/echo/v4"
+)
+
+// eventKeepAlive is how often an idle change stream sends a comment, so
+// proxies do not close it.
+const eventKeepAlive = 15 * time.Second
+
+// StreamChanges sends the notifications of sub to the client as server-sent
+// events until the client goes away or sub is closed. Changes are "change"
+// events carrying the database.Change JSON; a "resync" event tells the client
+// that changes were missed and it should reload.
+func StreamChanges(c echo.Context, sub *database.Subscription) error {
+	w := c.Response()
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("failed to clear write deadline: %w", err)
+	}
+
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case n, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if n.Missed {
+				_, err = fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+			} else {
+				_, err = fmt.Fprintf(w, "event: change\ndata: %s\n\n", n.Payload)
+			}
+		}
+		if err != nil {
+			return nil
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,229 @@
+package routing
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Route is what Router remembers about a route: the name of its handler
+// function and the middleware attached to it, outermost first.
+type Route struct {
+	Handler    string
+	Middleware []string
+}
+
+// Router is an Echo router that records the handler and middleware of every
+// route registered through it, which Echo does not keep once a route has
+// been added. Routes added through the embedded Echo directly, such as
+// Static, are served as usual but not recorded.
+type Router struct {
+	*echo.Echo
+
+	mu         sync.RWMutex
+	middleware []string
+	routes     map[string]Route
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{
+		Echo:   echo.New(),
+		routes: make(map[string]Route),
+	}
+}
+
+// Use adds router-level middleware. Echo runs it for every route, including
+// the ones registered before the call.
+func (r *Router) Use(middleware ...echo.MiddlewareFunc) {
+	r.Echo.Use(middleware...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, funcNames(middleware)...)
+}
+
+func (r *Router) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.Add(http.MethodGet, path, h, m...)
+}
+
+func (r *Router) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.Add(http.MethodPost, path, h, m...)
+}
+
+func (r *Router) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.Add(http.MethodPut, path, h, m...)
+}
+
+func (r *Router) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.Add(http.MethodPatch, path, h, m...)
+}
+
+func (r *Router) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.Add(http.MethodDelete, path, h, m...)
+}
+
+// Add registers a route and records its handler and middleware.
+func (r *Router) Add(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	route := r.Echo.Add(method, path, h, m...)
+	r.record(method, path, h, funcNames(m))
+	return route
+}
+
+// Group creates a route group whose routes are recorded by r.
+func (r *Router) Group(prefix string, m ...echo.MiddlewareFunc) *Group {
+	return &Group{
+		group:      r.Echo.Group(prefix, m...),
+		router:     r,
+		prefix:     prefix,
+		middleware: funcNames(m),
+	}
+}
+
+// Middleware returns the router-level middleware, which applies to every
+// route, recorded or not.
+func (r *Router) Middleware() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.middleware)
+}
+
+// Lookup returns the recorded route. Its Middleware starts with the
+// router-level middleware, followed by that of its groups and its own.
+func (r *Router) Lookup(method, path string) (Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	route, ok := r.routes[method+" "+path]
+	if !ok {
+		return Route{}, false
+	}
+	route.Middleware = append(slices.Clone(r.middleware), route.Middleware...)
+	return route, true
+}
+
+func (r *Router) record(method, path string, h echo.HandlerFunc, middleware []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes[method+" "+path] = Route{
+		Handler:    FuncName(h),
+		Middleware: middleware,
+	}
+}
+
+// Group is an Echo route group that records its routes in its Router.
+type Group struct {
+	group      *echo.Group
+	router     *Router
+	prefix     string
+	middleware []string
+}
+
+// Use adds middleware to the routes registered on the group afterwards.
+func (g *Group) Use(middleware ...echo.MiddlewareFunc) {
+	g.group.Use(middleware...)
+	g.middleware = append(g.middleware, funcNames(middleware)...)
+}
+
+func (g *Group) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.Add(http.MethodGet, path, h, m...)
+}
+
+func (g *Group) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.Add(http.MethodPost, path, h, m...)
+}
+
+func (g *Group) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.Add(http.MethodPut, path, h, m...)
+}
+
+func (g *Group) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.Add(http.MethodPatch, path, h, m...)
+}
+
+func (g *Group) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return g.Add(http.MethodDelete, path, h, m...)
+}
+
+// Add registers a route on the group and records it with the group's
+// middleware in front of its own.
+func (g *Group) Add(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	route := g.group.Add(method, path, h, m...)
+	g.router.record(method, g.prefix+path, h, append(slices.Clone(g.middleware), funcNames(m)...))
+	return route
+}
+
+// Group creates a subgroup that inherits the group's middleware.
+func (g *Group) Group(prefix string, m ...echo.MiddlewareFunc) *Group {
+	return &Group{
+		group:      g.group.Group(prefix, m...),
+		router:     g.router,
+		prefix:     g.prefix + prefix,
+		middleware: append(slices.Clone(g.middleware), funcNames(m)...),
+	}
+}
+
+// FuncName returns a short name for a handler or middleware function:
+// "TaskHandler.GetTask" for a method value, "middleware.CORSWithConfig" for
+// a closure returned by a constructor.
+func FuncName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return ShortFuncName(f.Name())
+}
+
+// ShortFuncName shortens a fully qualified function name as FuncName does.
+func ShortFuncName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		pkg := name[:i]
+		name = name[i+1:]
+
+		// "github.com/labstack/echo/v4.Static" -> "echo.Static"
+		if version, rest, ok := strings.Cut(name, "."); ok && isMajorVersion(version) {
+			name = path.Base(pkg) + "." + rest
+		}
+	}
+	name = strings.TrimSuffix(name, "-fm")
+
+	// Drop the ".func1" suffixes of closures
+	for {
+		i := strings.LastIndex(name, ".func")
+		if i < 0 || strings.Trim(name[i+len(".func"):], "0123456789.") != "" {
+			break
+		}
+		name = name[:i]
+	}
+
+	// "handler.(*TaskHandler).GetTask" -> "TaskHandler.GetTask"
+	if i := strings.Index(name, ".("); i >= 0 {
+		name = name[i+2:]
+		name = strings.Replace(strings.TrimPrefix(name, "*"), ")", "", 1)
+	}
+
+	return name
+}
+
+func isMajorVersion(s string) bool {
+	return len(s) > 1 && s[0] == 'v' && strings.Trim(s[1:], "0123456789") == ""
+}
+
+func funcNames(middleware []echo.MiddlewareFunc) []string {
+	names := make([]string, 0, len(middleware))
+	for _, m := range middleware {
+		names = append(names, FuncName(m))
+	}
+	return names
+}
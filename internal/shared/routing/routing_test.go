@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testHandler struct{}
+
+func (*testHandler) Get(c echo.Context) error { return nil }
+
+func auth(next echo.HandlerFunc) echo.HandlerFunc   { return next }
+func audit(next echo.HandlerFunc) echo.HandlerFunc  { return next }
+func limit(next echo.HandlerFunc) echo.HandlerFunc  { return next }
+func tracer(next echo.HandlerFunc) echo.HandlerFunc { return next }
+
+func TestRouterRecordsHandlerAndMiddleware(t *testing.T) {
+	h := &testHandler{}
+
+	r := New()
+	r.GET("/", h.Get).Name = "home"
+	api := r.Group("/api", limit)
+	items := api.Group("/items")
+	items.Use(audit)
+	items.POST("/create", h.Get, tracer).Name = "items.create"
+	r.Use(auth)
+
+	tests := []struct {
+		method     string
+		path       string
+		handler    string
+		middleware []string
+	}{
+		{http.MethodGet, "/", "testHandler.Get", []string{"routing.auth"}},
+		{http.MethodPost, "/api/items/create", "testHandler.Get",
+			[]string{"routing.auth", "routing.limit", "routing.audit", "routing.tracer"}},
+	}
+
+	for _, tt := range tests {
+		route, ok := r.Lookup(tt.method, tt.path)
+		if !ok {
+			t.Fatalf("%s %s not recorded", tt.method, tt.path)
+		}
+		if route.Handler != tt.handler {
+			t.Errorf("%s %s handler = %q, want %q", tt.method, tt.path, route.Handler, tt.handler)
+		}
+		if !slices.Equal(route.Middleware, tt.middleware) {
+			t.Errorf("%s %s middleware = %v, want %v", tt.method, tt.path, route.Middleware, tt.middleware)
+		}
+	}
+
+	// Naming a route still reaches Echo's reverse routing
+	if got := r.Reverse("items.create"); got != "/api/items/create" {
+		t.Errorf("Reverse = %q, want /api/items/create", got)
+	}
+}
+
+func TestShortFuncName(t *testing.T) {
+	tests := map[string]string{
+		"github.com/goku-m/main/apps/task/api/handler.(*TaskHandler).GetTask-fm":               "TaskHandler.GetTask",
+		"github.com/goku-m/main/internal/shared/middleware.(*AuthMiddleware).RequireAuthIP-fm": "AuthMiddleware.RequireAuthIP",
+		"github.com/labstack/echo/v4/middleware.CORSWithConfig.func1":                          "middleware.CORSWithConfig",
+		"github.com/labstack/echo/v4.StaticDirectoryHandler.func1":                             "echo.StaticDirectoryHandler",
+		"main.handler.func2.1": "main.handler",
+	}
+
+	for in, want := range tests {
+		if got := ShortFuncName(in); got != want {
+			t.Errorf("ShortFuncName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultHookTimeout bounds a lifecycle callback that does not set its own timeout.
+const DefaultHookTimeout = 15 * time.Second
+
+// Hook groups the optional lifecycle callbacks of a component such as an app
+// module. Start hooks run in registration order before the HTTP server accepts
+// traffic, Ready hooks run once every component has started, and Stop hooks
+// run in reverse order during shutdown.
+type Hook struct {
+	Name    string
+	Timeout time.Duration
+	OnStart func(ctx context.Context) error
+	OnReady func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// AddHook registers lifecycle callbacks. Hooks must be added before Start.
+func (s *Server) AddHook(h Hook) {
+	if s.root != nil {
+		s.root.AddHook(h)
+		return
+	}
+	s.hooks = append(s.hooks, h)
+}
+
+func (h Hook) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return DefaultHookTimeout
+}
+
+// runStartHooks starts every hook in order and then waits for each to report
+// ready. When a hook fails, the hooks that already started are stopped again.
+func (s *Server) runStartHooks() error {
+	for _, h := range s.hooks {
+		if err := s.runHook(context.Background(), h, "start", h.OnStart); err != nil {
+			_ = s.runStopHooks(context.Background())
+			return err
+		}
+		s.hooksMu.Lock()
+		s.started = append(s.started, h)
+		s.hooksMu.Unlock()
+	}
+
+	for _, h := range s.hooks {
+		if err := s.runHook(context.Background(), h, "ready", h.OnReady); err != nil {
+			_ = s.runStopHooks(context.Background())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runStopHooks stops started hooks in reverse order. A failing hook does not
+// prevent the remaining hooks from stopping.
+func (s *Server) runStopHooks(ctx context.Context) error {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
+	var errs []error
+	for i := len(s.started) - 1; i >= 0; i-- {
+		h := s.started[i]
+		if err := s.runHook(ctx, h, "stop", h.OnStop); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.started = nil
+
+	return errors.Join(errs...)
+}
+
+func (s *Server) runHook(parent context.Context, h Hook, phase string, fn func(ctx context.Context) error) error {
+	if fn == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(parent, h.timeout())
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	duration := time.Since(start)
+
+	if err != nil {
+		s.Logger.Error().
+			Err(err).
+			Str("hook", h.Name).
+			Str("phase", phase).
+			Dur("duration", duration).
+			Msg("lifecycle hook failed")
+		return fmt.Errorf("%s hook for %q failed: %w", phase, h.Name, err)
+	}
+
+	s.Logger.Info().
+		Str("hook", h.Name).
+		Str("phase", phase).
+		Dur("duration", duration).
+		Msg("lifecycle hook completed")
+	return nil
+}
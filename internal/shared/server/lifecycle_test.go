@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestServer() *Server {
+	logger := zerolog.Nop()
+	return &Server{Logger: &logger}
+}
+
+// recordingHook returns a hook that appends "<phase> <name>" to calls and
+// fails the phases listed in fail.
+func recordingHook(calls *[]string, name string, fail ...string) Hook {
+	run := func(phase string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			*calls = append(*calls, phase+" "+name)
+			if slices.Contains(fail, phase) {
+				return errors.New("boom")
+			}
+			return nil
+		}
+	}
+	return Hook{Name: name, OnStart: run("start"), OnReady: run("ready"), OnStop: run("stop")}
+}
+
+func TestHooksStartInOrderAndStopInReverse(t *testing.T) {
+	var calls []string
+	s := newTestServer()
+	s.AddHook(recordingHook(&calls, "a"))
+	s.AddHook(recordingHook(&calls, "b"))
+	s.AddHook(Hook{Name: "no callbacks"})
+	s.AddHook(recordingHook(&calls, "c"))
+
+	if err := s.runStartHooks(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.runStopHooks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start a", "start b", "start c",
+		"ready a", "ready b", "ready c",
+		"stop c", "stop b", "stop a",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	// Stopping again does not stop the hooks twice
+	calls = nil
+	if err := s.runStopHooks(context.Background()); err != nil || len(calls) != 0 {
+		t.Errorf("second stop = %v, calls %v", err, calls)
+	}
+}
+
+func TestFailedStartStopsStartedHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		failHook  string
+		failPhase string
+		want      []string
+	}{
+		{
+			name:      "start fails",
+			failHook:  "b",
+			failPhase: "start",
+			want:      []string{"start a", "start b", "stop a"},
+		},
+		{
+			name:      "ready fails",
+			failHook:  "b",
+			failPhase: "ready",
+			want:      []string{"start a", "start b", "start c", "ready a", "ready b", "stop c", "stop b", "stop a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := newTestServer()
+			for _, name := range []string{"a", "b", "c"} {
+				if name == tt.failHook {
+					s.AddHook(recordingHook(&calls, name, tt.failPhase))
+				} else {
+					s.AddHook(recordingHook(&calls, name))
+				}
+			}
+
+			err := s.runStartHooks()
+			if err == nil || !strings.Contains(err.Error(), tt.failPhase+` hook for "b" failed`) {
+				t.Fatalf("err = %v, want %s hook for b failed", err, tt.failPhase)
+			}
+			if !slices.Equal(calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailingStopHookDoesNotStopOthers(t *testing.T) {
+	var calls []string
+	s := newTestServer()
+	s.AddHook(recordingHook(&calls, "a"))
+	s.AddHook(recordingHook(&calls, "b", "stop"))
+	s.AddHook(recordingHook(&calls, "c"))
+
+	if err := s.runStartHooks(); err != nil {
+		t.Fatal(err)
+	}
+	calls = nil
+
+	err := s.runStopHooks(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `stop hook for "b" failed`) {
+		t.Errorf("err = %v, want stop hook for b failed", err)
+	}
+	if want := []string{"stop c", "stop b", "stop a"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{"default", 0, DefaultHookTimeout},
+		{"own timeout", time.Minute, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var left time.Duration
+			s := newTestServer()
+			s.AddHook(Hook{
+				Name:    "timed",
+				Timeout: tt.timeout,
+				OnStart: func(ctx context.Context) error {
+					deadline, ok := ctx.Deadline()
+					if !ok {
+						t.Fatal("start hook has no deadline")
+					}
+					left = time.Until(deadline)
+					return nil
+				},
+			})
+
+			if err := s.runStartHooks(); err != nil {
+				t.Fatal(err)
+			}
+			if left > tt.want || left < tt.want-time.Second {
+				t.Errorf("deadline in %s, want %s", left, tt.want)
+			}
+		})
+	}
+}
+
+func TestHooksOfModuleViewsRunOnRoot(t *testing.T) {
+	var calls []string
+	s := newTestServer()
+	s.AddHook(recordingHook(&calls, "root"))
+	s.WithDB(nil).WithDB(nil).AddHook(recordingHook(&calls, "module"))
+
+	if err := s.runStartHooks(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"start root", "start module", "ready root", "ready module"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
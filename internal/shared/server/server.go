@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/goku-m/main/internal/shared/config"
@@ -15,12 +16,20 @@ import (
 )
 
 type Server struct {
-	Config        *config.Config
-	Logger        *zerolog.Logger
-	DB            *database.Database
-	Redis         *redis.Client
-	httpServer    *http.Server
-	Job           *job.JobService
+	Config     *config.Config
+	Reloader   *config.Reloader
+	Logger     *zerolog.Logger
+	DB         *database.Database
+	Listener   *database.Listener
+	Redis      *redis.Client
+	httpServer *http.Server
+	Job        *job.JobService
+	hooks      []Hook
+	hooksMu    sync.Mutex
+	started    []Hook
+
+	// root is the server a module view was derived from by WithDB.
+	root *Server
 }
 
 func New(cfg *config.Config, logger *zerolog.Logger) (*Server, error) {
@@ -53,16 +62,44 @@ func New(cfg *config.Config, logger *zerolog.Logger) (*Server, error) {
 	}
 
 	server := &Server{
-		Config: cfg,
-		Logger: logger,
-		DB:     db,
-		Redis:  redisClient,
-		Job:    jobService,
+		Config:   cfg,
+		Reloader: config.NewReloader(cfg),
+		Logger:   logger,
+		DB:       db,
+		Listener: db.NewListener(),
+		Redis:    redisClient,
+		Job:      jobService,
 	}
 
+	server.AddHook(Hook{
+		Name:    "database listener",
+		OnStart: server.Listener.Start,
+		OnStop:  server.Listener.Stop,
+	})
+
 	return server, nil
 }
 
+// WithDB returns a view of the server for a module that uses db, typically
+// one scoped to the module's schema. The view shares every other component
+// with s, and hooks added to it are added to s.
+func (s *Server) WithDB(db *database.Database) *Server {
+	root := s
+	if s.root != nil {
+		root = s.root
+	}
+	return &Server{
+		Config:   s.Config,
+		Reloader: s.Reloader,
+		Logger:   s.Logger,
+		DB:       db,
+		Listener: s.Listener,
+		Redis:    s.Redis,
+		Job:      s.Job,
+		root:     root,
+	}
+}
+
 func (s *Server) SetupHTTPServer(handler http.Handler) {
 	s.httpServer = &http.Server{
 		Addr:         ":" + s.Config.Server.Port,
@@ -78,6 +115,10 @@ func (s *Server) Start() error {
 		return errors.New("HTTP server not initialized")
 	}
 
+	if err := s.runStartHooks(); err != nil {
+		return err
+	}
+
 	s.Logger.Info().
 		Str("port", s.Config.Server.Port).
 		Str("env", s.Config.Primary.Env).
@@ -91,6 +132,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
 
+	if err := s.runStopHooks(ctx); err != nil {
+		s.Logger.Error().Err(err).Msg("failed to stop lifecycle hooks cleanly")
+	}
+
 	if err := s.DB.Close(); err != nil {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
@@ -25,6 +25,20 @@ func ErrCode(err error) Code {
 	return Other
 }
 
+// IsRetryable reports whether err is a serialization failure or a deadlock,
+// after which the whole transaction can be run again.
+func IsRetryable(err error) bool {
+	code := ErrCode(err)
+	if code == Other {
+		// Not converted yet, as inside a transaction
+		var pgerr *pgconn.PgError
+		if errors.As(err, &pgerr) {
+			code = MapCode(pgerr.Code)
+		}
+	}
+	return code == SerializationFailure || code == DeadlockDetected
+}
+
 // ConvertPgError converts a pgconn.PgError to our custom Error type
 func ConvertPgError(src *pgconn.PgError) *Error {
 	return &Error{
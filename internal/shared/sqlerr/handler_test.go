@@ -0,0 +1,41 @@
+package sqlerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001", Severity: "ERROR"}
+	deadlock := &pgconn.PgError{Code: "40P01", Severity: "ERROR"}
+	unique := &pgconn.PgError{Code: "23505", Severity: "ERROR"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"raw serialization failure", serialization, true},
+		{"raw deadlock", deadlock, true},
+		{"wrapped raw serialization failure", fmt.Errorf("commit: %w", serialization), true},
+		{"mapped serialization failure", ConvertPgError(serialization), true},
+		{"mapped deadlock", ConvertPgError(deadlock), true},
+		{"wrapped mapped deadlock", fmt.Errorf("update: %w", ConvertPgError(deadlock)), true},
+		{"mapped without driver error", &Error{Code: SerializationFailure}, true},
+		{"raw unique violation", unique, false},
+		{"mapped unique violation", ConvertPgError(unique), false},
+		{"other error", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -22,10 +22,34 @@ type TestDB struct {
 	Config    *config.Config
 }
 
-// SetupTestDB creates a Postgres container and applies migrations
-func SetupTestDB(t *testing.T) (*TestDB, func()) {
+// Option configures SetupTestDB.
+type Option func(*setupOptions)
+
+type setupOptions struct {
+	seedEnv      string
+	seedProjects []string
+}
+
+// WithSeeds loads the seed files of env after migrating, for the given
+// projects or for every project when none is given:
+// SetupTestDB(t, WithSeeds("test")).
+func WithSeeds(env string, projects ...string) Option {
+	return func(o *setupOptions) {
+		o.seedEnv = env
+		o.seedProjects = projects
+	}
+}
+
+// SetupTestDB creates a Postgres container and applies migrations. The
+// database starts empty unless seeds are requested with WithSeeds.
+func SetupTestDB(t *testing.T, opts ...Option) (*TestDB, func()) {
 	t.Helper()
 
+	var options setupOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	ctx := context.Background()
 	dbName := fmt.Sprintf("test_db_%s", uuid.New().String()[:8])
 	dbUser := "testuser"
@@ -68,11 +92,10 @@ func SetupTestDB(t *testing.T) (*TestDB, func()) {
 			Host:            host,
 			Port:            port,
 			User:            dbUser,
-			Password:        dbPassword,
+			Password:        config.Secret(dbPassword),
 			Name:            dbName,
 			SSLMode:         "disable",
 			MaxOpenConns:    25,
-			MaxIdleConns:    25,
 			ConnMaxLifetime: 300,
 			ConnMaxIdleTime: 300,
 		},
@@ -125,6 +148,11 @@ func SetupTestDB(t *testing.T) (*TestDB, func()) {
 	err = database.Migrate(ctx, &logger, cfg)
 	require.NoError(t, err, "failed to apply database migrations")
 
+	if options.seedEnv != "" {
+		err = database.Seed(ctx, &logger, db.Pool, options.seedEnv, options.seedProjects...)
+		require.NoError(t, err, "failed to seed test database")
+	}
+
 	testDB := &TestDB{
 		Pool:      db.Pool,
 		Container: pgContainer,
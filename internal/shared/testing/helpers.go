@@ -11,8 +11,9 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// SetupTest prepares a test environment with a database and server
-func SetupTest(t *testing.T) (*TestDB, *server.Server, func()) {
+// SetupTest prepares a test environment with a database and server. The
+// options are passed on to SetupTestDB.
+func SetupTest(t *testing.T, opts ...Option) (*TestDB, *server.Server, func()) {
 	t.Helper()
 
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).
@@ -21,7 +22,7 @@ func SetupTest(t *testing.T) (*TestDB, *server.Server, func()) {
 		Timestamp().
 		Logger()
 
-	testDB, dbCleanup := SetupTestDB(t)
+	testDB, dbCleanup := SetupTestDB(t, opts...)
 
 	testServer := CreateTestServer(&logger, testDB)
 
@@ -0,0 +1,139 @@
+// Package trash purges the rows that modules keep in their trash, with
+// deleted_at set, once they are past the trash retention.
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/audit"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/goku-m/main/internal/shared/worker"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// PurgeInterval is how often rows past the trash retention are purged.
+const PurgeInterval = time.Hour
+
+// Actor is the audit actor of purged rows.
+const Actor = "system:trash-purge"
+
+// Purger permanently deletes the rows of one table that have been in the
+// trash for longer than the retention, auditing each of them. T is the row
+// type, scanned by column name, so purge entries carry the same fields as the
+// module's other audit entries. Its Start and Stop fit server.Hook.
+type Purger[T any] struct {
+	// OnPurge, when set, runs for every purged row in the transaction that
+	// deletes it, e.g. to add the module's purge event to the outbox like a
+	// purge by the user would. Without it automatic purges only reach the
+	// audit log.
+	OnPurge func(ctx context.Context, row *T) error
+
+	db        *database.Database
+	table     string
+	entity    string
+	id        func(row *T) string
+	retention time.Duration
+	logger    *zerolog.Logger
+	worker    *worker.Worker
+}
+
+// NewPurger returns the purger of table in the schema of db. entity names
+// the rows in the audit log and id returns the ID of a row. A retention of
+// zero or less keeps rows in the trash forever.
+func NewPurger[T any](db *database.Database, table, entity string, id func(row *T) string, retention time.Duration, logger *zerolog.Logger) *Purger[T] {
+	p := &Purger[T]{
+		db:        db,
+		table:     table,
+		entity:    entity,
+		id:        id,
+		retention: retention,
+		logger:    logger,
+	}
+	p.worker = worker.Every(table+" trash purge", PurgeInterval, p.purge)
+
+	return p
+}
+
+// Start launches the purge loop. The context only bounds startup; the loop
+// runs until Stop is called.
+func (p *Purger[T]) Start(ctx context.Context) error {
+	if p.retention <= 0 {
+		p.logger.Info().Str("table", p.table).Msg("trash retention disabled, keeping deleted rows forever")
+		return nil
+	}
+
+	return p.worker.Start(ctx)
+}
+
+// Stop cancels the purge loop and waits for it to exit or for ctx to expire.
+func (p *Purger[T]) Stop(ctx context.Context) error {
+	return p.worker.Stop(ctx)
+}
+
+func (p *Purger[T]) purge(ctx context.Context) {
+	purged, err := p.Purge(audit.WithActor(ctx, Actor), time.Now().Add(-p.retention))
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			p.logger.Error().Err(err).Str("table", p.table).Msg("failed to purge deleted rows")
+		}
+		return
+	}
+
+	if purged > 0 {
+		p.logger.Info().
+			Str("event", p.table+"_purged").
+			Int64("count", purged).
+			Dur("retention", p.retention).
+			Msgf("Purged %s from the trash", p.table)
+	}
+}
+
+// Purge permanently deletes the rows moved to the trash before the given
+// time and returns how many were deleted.
+func (p *Purger[T]) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var purged []T
+	err := p.db.InTx(ctx, func(ctx context.Context) error {
+		rows, err := p.db.Writer(ctx).Query(ctx, p.statement(), pgx.NamedArgs{
+			"before": before,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge deleted %s: %w", p.table, err)
+		}
+
+		if purged, err = pgx.CollectRows(rows, pgx.RowToStructByName[T]); err != nil {
+			return fmt.Errorf("failed to collect rows from table:%s: %w", p.table, err)
+		}
+
+		for i := range purged {
+			row := &purged[i]
+			if err := audit.Record(ctx, p.db, audit.OperationPurge, p.entity, p.id(row), row, nil); err != nil {
+				return err
+			}
+			if p.OnPurge != nil {
+				if err := p.OnPurge(ctx, row); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(purged)), nil
+}
+
+func (p *Purger[T]) statement() string {
+	return `
+		DELETE FROM ` + pgx.Identifier{p.table}.Sanitize() + `
+		WHERE
+			deleted_at < @before
+		RETURNING
+		*
+	`
+}
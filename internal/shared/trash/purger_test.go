@@ -0,0 +1,163 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goku-m/main/internal/shared/audit"
+	"github.com/goku-m/main/internal/shared/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+type row struct {
+	ID        string    `db:"id"`
+	DeletedAt time.Time `db:"deleted_at"`
+}
+
+func TestPurgerStatementQuotesTable(t *testing.T) {
+	p := NewPurger(nil, `tasks"; DROP TABLE x; --`, "task", func(r *row) string { return r.ID }, 0, nil)
+
+	stmt := p.statement()
+	if !strings.Contains(stmt, `DELETE FROM "tasks""; DROP TABLE x; --"`) {
+		t.Errorf("table is not quoted:\n%s", stmt)
+	}
+}
+
+// fakeTx stands in for the transaction the purge runs in: the DELETE returns
+// deleted, and Exec records the audit entries it is given.
+type fakeTx struct {
+	pgx.Tx
+	deleted   []row
+	before    time.Time
+	audited   [][]any
+	committed bool
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) { return tx, nil }
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error { return nil }
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.before = args[0].(pgx.NamedArgs)["before"].(time.Time)
+	return &fakeRows{rows: tx.deleted, at: -1}, nil
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if strings.Contains(sql, "audit_log") {
+		tx.audited = append(tx.audited, args)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+// fakeRows returns rows as the columns of row.
+type fakeRows struct {
+	pgx.Rows
+	rows []row
+	at   int
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	return []pgconn.FieldDescription{{Name: "id"}, {Name: "deleted_at"}}
+}
+
+func (r *fakeRows) Next() bool {
+	r.at++
+	return r.at < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	values := []any{r.rows[r.at].ID, r.rows[r.at].DeletedAt}
+	for i, value := range values {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+func newTestPurger(retention time.Duration) *Purger[row] {
+	logger := zerolog.Nop()
+	return NewPurger(&database.Database{}, "tasks", "task", func(r *row) string { return r.ID }, retention, &logger)
+}
+
+func TestPurgeAuditsPurgedRows(t *testing.T) {
+	now := time.Now()
+	tx := &fakeTx{deleted: []row{
+		{ID: "t1", DeletedAt: now.Add(-40 * 24 * time.Hour)},
+		{ID: "t2", DeletedAt: now.Add(-31 * 24 * time.Hour)},
+	}}
+	p := newTestPurger(30 * 24 * time.Hour)
+
+	var hooked []string
+	p.OnPurge = func(ctx context.Context, r *row) error {
+		if _, ok := database.TxFromContext(ctx); !ok {
+			t.Error("OnPurge runs outside the purge transaction")
+		}
+		hooked = append(hooked, r.ID)
+		return nil
+	}
+
+	// The loop purges the rows deleted more than the retention ago
+	p.purge(database.WithTx(context.Background(), tx))
+
+	if want := now.Add(-30 * 24 * time.Hour); tx.before.Before(want) || tx.before.After(time.Now().Add(-30*24*time.Hour)) {
+		t.Errorf("purged rows deleted before %s, want about %s", tx.before, want)
+	}
+	if !tx.committed {
+		t.Error("purge was not committed")
+	}
+
+	if len(tx.audited) != 2 {
+		t.Fatalf("%d audit entries, want 2", len(tx.audited))
+	}
+	for i, id := range []string{"t1", "t2"} {
+		args := tx.audited[i]
+		actor, _ := args[4].(*string)
+		if args[1] != "task" || args[2] != id || args[3] != audit.OperationPurge || actor == nil || *actor != Actor {
+			t.Errorf("audit entry %d = %v, want a purge of task %s by %s", i, args, id, Actor)
+		}
+	}
+	if len(hooked) != 2 || hooked[0] != "t1" || hooked[1] != "t2" {
+		t.Errorf("OnPurge ran for %v, want t1 and t2", hooked)
+	}
+}
+
+func TestPurgeFailsWhenOnPurgeFails(t *testing.T) {
+	tx := &fakeTx{deleted: []row{{ID: "t1"}}}
+	p := newTestPurger(time.Hour)
+	errHook := errors.New("outbox unavailable")
+	p.OnPurge = func(ctx context.Context, r *row) error { return errHook }
+
+	purged, err := p.Purge(database.WithTx(context.Background(), tx), time.Now())
+	if !errors.Is(err, errHook) || purged != 0 {
+		t.Errorf("Purge = %d, %v, want 0 and the OnPurge error", purged, err)
+	}
+	if tx.committed {
+		t.Error("purge was committed despite the failure")
+	}
+}
+
+func TestDisabledRetentionKeepsTrash(t *testing.T) {
+	p := newTestPurger(0)
+
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing was started, so there is nothing to stop
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package urls builds links that stay correct wherever a module is mounted.
+//
+// The gateway records the prefix and external host a request was routed
+// through, and each module router registers itself so named Echo routes can
+// be reversed. Handlers and templ components then ask for URLs by route name
+// instead of hard-coding the module prefix.
+package urls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// Mount describes where the gateway mounted the module serving a request.
+type Mount struct {
+	// Prefix is the path prefix stripped by the gateway, "" for the root.
+	Prefix string
+	// Scheme and Host describe the externally visible origin of the request.
+	Scheme string
+	Host   string
+	// HostMatch is the host label captured by a wildcard host route, e.g.
+	// "acme" for acme.sites.example.local routed by "*.sites.example.local".
+	HostMatch string
+}
+
+type contextKey int
+
+const (
+	mountKey contextKey = iota
+	routerKey
+)
+
+// WithMount returns a copy of ctx carrying the mount information. Mounts
+// nest, so a prefix recorded by an outer gateway is kept in front of the new one.
+func WithMount(ctx context.Context, m Mount) context.Context {
+	if parent, ok := ctx.Value(mountKey).(Mount); ok {
+		m.Prefix = parent.Prefix + m.Prefix
+		if m.Scheme == "" {
+			m.Scheme = parent.Scheme
+		}
+		if m.Host == "" {
+			m.Host = parent.Host
+		}
+		if m.HostMatch == "" {
+			m.HostMatch = parent.HostMatch
+		}
+	}
+	if m.Prefix == "/" {
+		m.Prefix = ""
+	}
+	return context.WithValue(ctx, mountKey, m)
+}
+
+// MountFrom returns the mount information stored in ctx. Requests that did
+// not pass through the gateway report the root mount.
+func MountFrom(ctx context.Context) Mount {
+	m, _ := ctx.Value(mountKey).(Mount)
+	return m
+}
+
+// TrustedProxies are the networks of the proxies in front of the gateway.
+// Only requests arriving from them may set the origin that MountFromRequest
+// reports through X-Forwarded-Proto and X-Forwarded-Host.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDR ranges such as "10.0.0.0/8" and single
+// addresses such as "127.0.0.1".
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: not an IP address or CIDR range", value)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// Trusts reports whether r arrived directly from a trusted proxy.
+func (t TrustedProxies) Trusts(r *http.Request) bool {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := peer.Addr().Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// MountFromRequest describes the origin of r as seen by the client. The
+// X-Forwarded-Proto and X-Forwarded-Host headers are only honoured when r
+// comes from one of the trusted proxies, as any client can send them.
+func MountFromRequest(r *http.Request, prefix string, trusted TrustedProxies) Mount {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	if trusted.Trusts(r) {
+		if proto := r.Header.Get(echo.HeaderXForwardedProto); proto != "" {
+			scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+		}
+		if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" {
+			host = strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	return Mount{Prefix: prefix, Scheme: scheme, Host: host}
+}
+
+// Middleware exposes router to Route and Absolute for the rest of the request.
+// Register it with router.Pre so it also applies to unmatched routes.
+func Middleware(router *echo.Echo) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := context.WithValue(c.Request().Context(), routerKey, router)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// Path prefixes a module-relative path with the mount prefix.
+func Path(ctx context.Context, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	prefix := MountFrom(ctx).Prefix
+	if prefix != "" && path == "/" {
+		return prefix
+	}
+	return prefix + path
+}
+
+// Route resolves a named route of the current module router to a path that
+// includes the mount prefix. Params fill the route's path parameters in order.
+// Unknown route names resolve to the module root and are logged as errors
+// with the request's logger, as they are a bug in the caller.
+func Route(ctx context.Context, name string, params ...interface{}) string {
+	router, ok := ctx.Value(routerKey).(*echo.Echo)
+	if !ok {
+		return Path(ctx, "/")
+	}
+
+	path := router.Reverse(name, params...)
+	if path == "" {
+		zerolog.Ctx(ctx).Error().Str("route", name).Msg("unknown route name, linking to the module root")
+		return Path(ctx, "/")
+	}
+	return Path(ctx, path)
+}
+
+// Absolute resolves a named route to a full external URL including scheme and host.
+func Absolute(ctx context.Context, name string, params ...interface{}) string {
+	m := MountFrom(ctx)
+	path := Route(ctx, name, params...)
+	if m.Host == "" {
+		return path
+	}
+	return m.Scheme + "://" + m.Host + path
+}
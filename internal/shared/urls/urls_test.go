@@ -0,0 +1,208 @@
+package urls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+func TestWithMountNests(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts []Mount
+		want   Mount
+	}{
+		{"none", nil, Mount{}},
+		{"root prefix", []Mount{{Prefix: "/"}}, Mount{}},
+		{"single", []Mount{{Prefix: "/task", Scheme: "https", Host: "example.com"}}, Mount{Prefix: "/task", Scheme: "https", Host: "example.com"}},
+		{
+			name:   "inner prefix follows outer",
+			mounts: []Mount{{Prefix: "/edge", Scheme: "https", Host: "example.com", HostMatch: "acme"}, {Prefix: "/task"}},
+			want:   Mount{Prefix: "/edge/task", Scheme: "https", Host: "example.com", HostMatch: "acme"},
+		},
+		{
+			name:   "inner origin wins",
+			mounts: []Mount{{Prefix: "/edge", Scheme: "https", Host: "example.com"}, {Prefix: "/task", Scheme: "http", Host: "internal"}},
+			want:   Mount{Prefix: "/edge/task", Scheme: "http", Host: "internal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, m := range tt.mounts {
+				ctx = WithMount(ctx, m)
+			}
+			if got := MountFrom(ctx); got != tt.want {
+				t.Errorf("MountFrom = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{"", "/", "/"},
+		{"", "public/app.css", "/public/app.css"},
+		{"/task", "/", "/task"},
+		{"/task", "/public/app.css", "/task/public/app.css"},
+		{"/task", "update/1", "/task/update/1"},
+	}
+
+	for _, tt := range tests {
+		ctx := WithMount(context.Background(), Mount{Prefix: tt.prefix})
+		if got := Path(ctx, tt.path); got != tt.want {
+			t.Errorf("Path(%q under %q) = %q, want %q", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestRouteAndAbsolute(t *testing.T) {
+	router := echo.New()
+	router.GET("/", func(c echo.Context) error { return nil }).Name = "home"
+	router.GET("/update/:id", func(c echo.Context) error { return nil }).Name = "edit"
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	ctx := context.WithValue(context.Background(), routerKey, router)
+	ctx = WithMount(ctx, Mount{Prefix: "/task", Scheme: "https", Host: "example.com"})
+	ctx = logger.WithContext(ctx)
+
+	tests := []struct {
+		name     string
+		params   []interface{}
+		want     string
+		absolute string
+	}{
+		{"home", nil, "/task", "https://example.com/task"},
+		{"edit", []interface{}{"7"}, "/task/update/7", "https://example.com/task/update/7"},
+		{"missing", nil, "/task", "https://example.com/task"},
+	}
+
+	for _, tt := range tests {
+		if got := Route(ctx, tt.name, tt.params...); got != tt.want {
+			t.Errorf("Route(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := Absolute(ctx, tt.name, tt.params...); got != tt.absolute {
+			t.Errorf("Absolute(%s) = %q, want %q", tt.name, got, tt.absolute)
+		}
+	}
+
+	if !strings.Contains(logs.String(), `"route":"missing"`) {
+		t.Errorf("unknown route was not logged: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), `"route":"home"`) {
+		t.Errorf("known route was logged: %s", logs.String())
+	}
+}
+
+func TestMountFromRequest(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		headers    map[string]string
+		want       Mount
+	}{
+		{
+			name:       "plain request",
+			remoteAddr: "203.0.113.5:4000",
+			want:       Mount{Prefix: "/task", Scheme: "http", Host: "app.local"},
+		},
+		{
+			name:       "tls request",
+			remoteAddr: "203.0.113.5:4000",
+			tls:        true,
+			want:       Mount{Prefix: "/task", Scheme: "https", Host: "app.local"},
+		},
+		{
+			name:       "forwarded by trusted range",
+			remoteAddr: "10.1.2.3:4000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "example.com"},
+			want:       Mount{Prefix: "/task", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "forwarded by trusted address takes the first entry",
+			remoteAddr: "192.168.1.1:4000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": " example.com , proxy.local"},
+			want:       Mount{Prefix: "/task", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "forwarded by trusted ipv6 address",
+			remoteAddr: "[::1]:4000",
+			headers:    map[string]string{"X-Forwarded-Host": "example.com"},
+			want:       Mount{Prefix: "/task", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded by ipv4 mapped trusted address",
+			remoteAddr: "[::ffff:10.0.0.1]:4000",
+			headers:    map[string]string{"X-Forwarded-Host": "example.com"},
+			want:       Mount{Prefix: "/task", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded by untrusted client is ignored",
+			remoteAddr: "203.0.113.5:4000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			want:       Mount{Prefix: "/task", Scheme: "http", Host: "app.local"},
+		},
+		{
+			name:       "neighbour of trusted address is untrusted",
+			remoteAddr: "192.168.1.2:4000",
+			headers:    map[string]string{"X-Forwarded-Host": "evil.example"},
+			want:       Mount{Prefix: "/task", Scheme: "http", Host: "app.local"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://app.local/task/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			if got := MountFromRequest(req, "/task", trusted); got != tt.want {
+				t.Errorf("MountFromRequest = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Without trusted proxies the headers are never honoured
+	req := httptest.NewRequest(http.MethodGet, "http://app.local/", nil)
+	req.RemoteAddr = "10.1.2.3:4000"
+	req.Header.Set("X-Forwarded-Host", "evil.example")
+	if got := MountFromRequest(req, "", nil); got.Host != "app.local" {
+		t.Errorf("host = %q without trusted proxies, want app.local", got.Host)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 127.0.0.1 ", "fd00::/8"}); err != nil {
+		t.Errorf("valid proxies: %v", err)
+	}
+	for _, invalid := range []string{"proxy.local", "10.0.0.0/33", ""} {
+		if _, err := ParseTrustedProxies([]string{invalid}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) did not fail", invalid)
+		}
+	}
+}
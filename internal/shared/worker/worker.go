@@ -0,0 +1,94 @@
+// Package worker runs the background loops of modules, such as schedulers
+// and relays, between the OnStart and OnStop lifecycle hooks of the server.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRunning is returned when starting a worker that is already running.
+var ErrRunning = errors.New("already running")
+
+// Worker runs a loop in its own goroutine from Start until Stop. Its Start
+// and Stop fit server.Hook.
+type Worker struct {
+	name string
+	loop func(ctx context.Context)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a worker running loop, which must return once its ctx is
+// cancelled.
+func New(name string, loop func(ctx context.Context)) *Worker {
+	return &Worker{name: name, loop: loop}
+}
+
+// Every returns a worker calling fn right away and then every interval. A
+// call that takes longer than interval delays the next one rather than
+// overlapping it.
+func Every(name string, interval time.Duration, fn func(ctx context.Context)) *Worker {
+	return New(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			fn(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Start launches the loop. The context only bounds startup; the loop runs
+// until Stop is called.
+func (w *Worker) Start(_ context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return fmt.Errorf("%s: %w", w.name, ErrRunning)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		w.loop(ctx)
+	}(w.done)
+
+	return nil
+}
+
+// Stop cancels the loop and waits for it to exit or for ctx to expire. A
+// stopped worker can be started again.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
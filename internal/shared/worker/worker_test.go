@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEveryRunsRightAwayAndOnEachTick(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	w := Every("test", 10*time.Millisecond, func(ctx context.Context) {
+		calls <- struct{}{}
+	})
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop(context.Background())
+
+	for i := range 3 {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("call %d did not happen", i+1)
+		}
+	}
+}
+
+func TestStartTwice(t *testing.T) {
+	w := New("relay", func(ctx context.Context) { <-ctx.Done() })
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop(context.Background())
+
+	err := w.Start(context.Background())
+	if !errors.Is(err, ErrRunning) || err.Error() != "relay: already running" {
+		t.Fatalf("second Start = %v, want relay: already running", err)
+	}
+}
+
+func TestStopWaitsForLoop(t *testing.T) {
+	var exited atomic.Bool
+	w := New("test", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		exited.Store(true)
+	})
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !exited.Load() {
+		t.Error("Stop returned before the loop exited")
+	}
+
+	// Stopping again is a no-op, and the worker can be restarted
+	if err := w.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop = %v", err)
+	}
+	if err := w.Start(context.Background()); err != nil {
+		t.Errorf("restart = %v", err)
+	}
+	w.Stop(context.Background())
+}
+
+func TestStopGivesUpWithContext(t *testing.T) {
+	release := make(chan struct{})
+	w := New("test", func(ctx context.Context) {
+		<-release
+	})
+	defer close(release)
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want deadline exceeded", err)
+	}
+}